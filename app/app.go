@@ -1,9 +1,13 @@
 package app
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/libs4go/scf4go"
 	"github.com/libs4go/scf4go/reader/file"
@@ -11,6 +15,16 @@ import (
 	"github.com/libs4go/smf4go"
 )
 
+// app exit codes
+const (
+	ExitOK     = 0 // app exit normally
+	ExitConfig = 1 // load config error
+	ExitLogger = 2 // set slf4go config error
+	ExitStart  = 3 // start mesh error
+	ExitStop   = 4 // stop mesh error
+	ExitCheck  = 5 // validate mesh error
+)
+
 func isDir(path string) bool {
 	fi, err := os.Stat(path)
 
@@ -21,42 +35,124 @@ func isDir(path string) bool {
 	return fi.IsDir()
 }
 
-// Run start a smf4go app
+func exit(code int, fmtstr string, args ...interface{}) {
+	fmt.Fprintln(os.Stderr, fmt.Sprintf(fmtstr, args...))
+	os.Exit(code)
+}
+
+// Run start a smf4go app, the app stops on SIGINT or SIGTERM, and reloads config on SIGHUP or
+// config file changes, which are polled every smf4go.app.watch duration (default 5s, 0s disable it)
 func Run(appname string) {
 	configpath := flag.String("config", fmt.Sprintf("./%s.json", appname), "special the mesh app config file")
 
+	check := flag.Bool("check", false, "validate the mesh app config and exit without starting it")
+
 	flag.Parse()
 
+	config, err := loadConfig(*configpath)
+
+	if err != nil {
+		exit(ExitConfig, "%s", err)
+	}
+
+	if err := slf4go.Config(config.SubConfig("slf4go")); err != nil {
+		exit(ExitLogger, "set slf4go config error: %s", err)
+	}
+
+	if *check {
+		if err := smf4go.Builder().Validate(config); err != nil {
+			exit(ExitCheck, "check app %s config error: %s", appname, err)
+		}
+
+		fmt.Printf("check app %s config -- success\n", appname)
+		os.Exit(ExitOK)
+	}
+
+	os.Exit(run(appname, *configpath, config))
+}
+
+func loadConfig(configpath string) (scf4go.Config, error) {
 	config := scf4go.New()
 
-	if isDir(*configpath) {
-		if err := config.Load(file.New(file.Dir(*configpath))); err != nil {
-			println(fmt.Sprintf("load config from directory %s %s", *configpath, err))
-			return
+	if isDir(configpath) {
+		if err := config.Load(file.New(file.Dir(configpath))); err != nil {
+			return nil, fmt.Errorf("load config from directory %s %s", configpath, err)
 		}
 	} else {
-		if err := config.Load(file.New(file.File(*configpath))); err != nil {
-			println(fmt.Sprintf("load config file %s %s", *configpath, err))
-			return
+		if err := config.Load(file.New(file.File(configpath))); err != nil {
+			return nil, fmt.Errorf("load config file %s %s", configpath, err)
 		}
 	}
 
-	if err := slf4go.Config(config.SubConfig("slf4go")); err != nil {
-		println(fmt.Sprintf("set slf4go config error: %s", err))
-		return
-	}
+	return config, nil
+}
 
+func run(appname string, configpath string, config scf4go.Config) int {
 	logger := slf4go.Get(appname)
 	defer slf4go.Sync()
 
+	// register signal handler before starting mesh, avoid losing signals during startup
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	defer signal.Stop(reloads)
+
 	logger.I("start app {@app}", appname)
 
-	if err := smf4go.Builder().Start(config); err != nil {
-		logger.I("start gomesh error: \n{@err}", err)
-		return
+	builder := smf4go.Builder()
+
+	if err := builder.Start(config); err != nil {
+		logger.E("start gomesh error: \n{@err}", err)
+		return ExitStart
 	}
 
 	logger.I("start app {@app} -- success", appname)
 
-	<-make(chan int)
+	closed := make(chan struct{})
+	defer close(closed)
+
+	changes := watchConfig(configpath, config.Get("smf4go", "app", "watch").Duration(time.Second*5), closed)
+
+	var sig os.Signal
+
+	for sig == nil {
+		select {
+		case sig = <-signals:
+			continue
+		case reload := <-reloads:
+			logger.I("app {@app} receive signal {@signal}, reloading config", appname, reload.String())
+		case <-changes:
+			logger.I("app {@app} config {@path} changed, reloading config", appname, configpath)
+		}
+
+		if reloaded, err := reloadConfig(builder, configpath); err != nil {
+			logger.E("reload app {@app} config error: \n{@err}", appname, err)
+		} else {
+			config = reloaded
+			logger.I("reload app {@app} config -- success", appname)
+		}
+	}
+
+	logger.I("app {@app} receive signal {@signal}, stopping", appname, sig.String())
+
+	if err := stop(builder, config); err != nil {
+		logger.E("stop app {@app} error: \n{@err}", appname, err)
+		return ExitStop
+	}
+
+	logger.I("stop app {@app} -- success", appname)
+
+	return ExitOK
+}
+
+func stop(builder smf4go.MeshBuilder, config scf4go.Config) error {
+	grace := config.Get("smf4go", "app", "grace").Duration(time.Second * 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	return builder.Stop(ctx)
 }
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/smf4go"
+)
+
+// configVersion get config file or directory files modification version
+func configVersion(configpath string) string {
+	fi, err := os.Stat(configpath)
+
+	if err != nil {
+		return ""
+	}
+
+	if !fi.IsDir() {
+		return fmt.Sprintf("%d:%d", fi.ModTime().UnixNano(), fi.Size())
+	}
+
+	files, err := ioutil.ReadDir(configpath)
+
+	if err != nil {
+		return ""
+	}
+
+	var version string
+
+	for _, file := range files {
+		version += fmt.Sprintf("%s:%d:%d;", file.Name(), file.ModTime().UnixNano(), file.Size())
+	}
+
+	return version
+}
+
+// watchConfig poll config file or directory changes by interval until closed,
+// the watching is disabled if interval is not positive
+func watchConfig(configpath string, interval time.Duration, closed chan struct{}) <-chan struct{} {
+	if interval <= 0 {
+		return nil
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		version := configVersion(configpath)
+
+		for {
+			select {
+			case <-closed:
+				return
+			case <-ticker.C:
+			}
+
+			current := configVersion(configpath)
+
+			if current == version {
+				continue
+			}
+
+			version = current
+
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes
+}
+
+// reloadConfig load config from path and apply it to mesh
+func reloadConfig(builder smf4go.MeshBuilder, configpath string) (scf4go.Config, error) {
+	config, err := loadConfig(configpath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := builder.Reload(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
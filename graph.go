@@ -0,0 +1,284 @@
+package smf4go
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/libs4go/errors"
+)
+
+// injectFields get the fields with inject tag of service type, which must be struct pointer
+func injectFields(serviceType reflect.Type) []reflect.StructField {
+	if serviceType == nil || serviceType.Kind() != reflect.Ptr || serviceType.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	serviceType = serviceType.Elem()
+
+	var fields []reflect.StructField
+
+	for i := 0; i < serviceType.NumField(); i++ {
+		if _, ok := serviceType.Field(i).Tag.Lookup("inject"); ok {
+			fields = append(fields, serviceType.Field(i))
+		}
+	}
+
+	return fields
+}
+
+// dependencies get service dependencies declared by `inject:"name"` struct tags,
+// the `inject:"*"` tags depend on all services match the field type
+func dependencies(entry ServiceRegisterEntry, services []ServiceRegisterEntry) []string {
+	var names []string
+
+	for _, field := range injectFields(reflect.TypeOf(entry.Service)) {
+		name := field.Tag.Get("inject")
+
+		if name != injectByType {
+			names = append(names, name)
+			continue
+		}
+
+		target := field.Type
+
+		if target.Kind() == reflect.Slice {
+			target = target.Elem()
+		}
+
+		for _, matched := range assignable(services, target, entry.Name) {
+			names = append(names, matched.Name)
+		}
+	}
+
+	return names
+}
+
+// dependencyGraph directed graph, the edge from node to its dependencies
+type dependencyGraph struct {
+	nodes []string            // order nodes
+	edges map[string][]string // dependencies of node
+}
+
+func newDependencyGraph() *dependencyGraph {
+	return &dependencyGraph{
+		edges: make(map[string][]string),
+	}
+}
+
+// newServiceGraph create dependency graph of services by their inject tags
+func newServiceGraph(services []ServiceRegisterEntry) *dependencyGraph {
+	graph := newDependencyGraph()
+
+	for _, entry := range services {
+		graph.addNode(entry.Name, dependencies(entry, services)...)
+	}
+
+	return graph
+}
+
+func (graph *dependencyGraph) addNode(name string, dependencies ...string) {
+	if _, ok := graph.edges[name]; !ok {
+		graph.nodes = append(graph.nodes, name)
+	}
+
+	graph.edges[name] = append(graph.edges[name], dependencies...)
+}
+
+func (graph *dependencyGraph) contains(name string) bool {
+	_, ok := graph.edges[name]
+	return ok
+}
+
+// reduce create sub graph only contains nodes accepted by keep function,
+// the dependency through removed nodes will be kept as direct edge
+func (graph *dependencyGraph) reduce(keep func(name string) bool) *dependencyGraph {
+	reduced := newDependencyGraph()
+
+	for _, node := range graph.nodes {
+		if !keep(node) {
+			continue
+		}
+
+		var dependencies []string
+
+		visited := map[string]bool{node: true}
+
+		stack := append([]string(nil), graph.edges[node]...)
+
+		for len(stack) > 0 {
+			current := stack[0]
+			stack = stack[1:]
+
+			if visited[current] || !graph.contains(current) {
+				continue
+			}
+
+			visited[current] = true
+
+			if keep(current) {
+				dependencies = append(dependencies, current)
+				continue
+			}
+
+			stack = append(stack, graph.edges[current]...)
+		}
+
+		reduced.addNode(node, dependencies...)
+	}
+
+	return reduced
+}
+
+// levels sort graph nodes topologically, dependencies first. nodes in the same level
+// are independent of each other and keep the graph insertion order, returns ErrCycle if
+// any cycle found
+func (graph *dependencyGraph) levels() ([][]string, error) {
+	return graph.sortLevels(func(cycle []string) error {
+		return errors.Wrap(ErrCycle, "dependency cycle: %s", strings.Join(cycle, " -> "))
+	})
+}
+
+// sortLevels sort graph nodes topologically like levels, onCycle is called with the cycle path when
+// no node is ready. the sorting is aborted if onCycle returns error, otherwise the cycle is broken by
+// taking the cycle node first inserted as the next level
+func (graph *dependencyGraph) sortLevels(onCycle func(cycle []string) error) ([][]string, error) {
+
+	sorted := make(map[string]bool)
+
+	var levels [][]string
+
+	for len(sorted) < len(graph.nodes) {
+		var level []string
+
+		for _, node := range graph.nodes {
+			if sorted[node] {
+				continue
+			}
+
+			if graph.ready(node, sorted) {
+				level = append(level, node)
+			}
+		}
+
+		if len(level) == 0 {
+			cycle := graph.cycle(sorted)
+
+			if err := onCycle(cycle); err != nil {
+				return nil, err
+			}
+
+			inCycle := make(map[string]bool)
+
+			for _, node := range cycle {
+				inCycle[node] = true
+			}
+
+			for _, node := range graph.nodes {
+				if inCycle[node] {
+					level = append(level, node)
+					break
+				}
+			}
+		}
+
+		for _, node := range level {
+			sorted[node] = true
+		}
+
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+// sort sort graph nodes topologically, dependencies first
+func (graph *dependencyGraph) sort() ([]string, error) {
+	levels, err := graph.levels()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []string
+
+	for _, level := range levels {
+		nodes = append(nodes, level...)
+	}
+
+	return nodes, nil
+}
+
+// prioritySort sort graph nodes topologically, the ready node with lowest priority is taken first,
+// nodes with same priority keep the graph insertion order
+func (graph *dependencyGraph) prioritySort(priority func(node string) int) ([]string, error) {
+
+	sorted := make(map[string]bool)
+
+	var nodes []string
+
+	for len(nodes) < len(graph.nodes) {
+		next := ""
+
+		for _, node := range graph.nodes {
+			if sorted[node] || !graph.ready(node, sorted) {
+				continue
+			}
+
+			if next == "" || priority(node) < priority(next) {
+				next = node
+			}
+		}
+
+		if next == "" {
+			return nil, errors.Wrap(ErrCycle, "dependency cycle: %s", strings.Join(graph.cycle(sorted), " -> "))
+		}
+
+		sorted[next] = true
+		nodes = append(nodes, next)
+	}
+
+	return nodes, nil
+}
+
+// ready check if all dependencies of node are sorted
+func (graph *dependencyGraph) ready(node string, sorted map[string]bool) bool {
+	for _, dependency := range graph.edges[node] {
+		if dependency != node && graph.contains(dependency) && !sorted[dependency] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cycle find one cycle path in the unsorted nodes
+func (graph *dependencyGraph) cycle(sorted map[string]bool) []string {
+	var path []string
+
+	index := make(map[string]int)
+
+	var current string
+
+	for _, node := range graph.nodes {
+		if !sorted[node] {
+			current = node
+			break
+		}
+	}
+
+	for {
+		if i, ok := index[current]; ok {
+			return append(path[i:], current)
+		}
+
+		index[current] = len(path)
+		path = append(path, current)
+
+		for _, dependency := range graph.edges[current] {
+			if dependency != current && graph.contains(dependency) && !sorted[dependency] {
+				current = dependency
+				break
+			}
+		}
+	}
+}
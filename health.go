@@ -0,0 +1,70 @@
+package smf4go
+
+import "context"
+
+// HealthStatus service health status
+type HealthStatus int
+
+// health status enum
+const (
+	HealthUnknown HealthStatus = iota
+	HealthServing
+	HealthNotServing
+)
+
+func (status HealthStatus) String() string {
+	switch status {
+	case HealthServing:
+		return "SERVING"
+	case HealthNotServing:
+		return "NOT_SERVING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// HealthChecker service which can report its health status,
+// services not implement HealthChecker are serving after mesh started
+type HealthChecker interface {
+	Service
+	Health(ctx context.Context) HealthStatus
+}
+
+// HealthReport the mesh health report
+type HealthReport struct {
+	Status   HealthStatus            // aggregate status of all services
+	Services map[string]HealthStatus // per service status
+}
+
+func (builder *meshBuilderImpl) Health(ctx context.Context) *HealthReport {
+
+	report := &HealthReport{
+		Status:   HealthNotServing,
+		Services: make(map[string]HealthStatus),
+	}
+
+	services, ok := builder.serving.Load().([]ServiceRegisterEntry)
+
+	if !ok || !builder.started.Load().(bool) {
+		return report
+	}
+
+	report.Status = HealthServing
+
+	for _, entry := range services {
+		status := HealthServing
+
+		if checker, ok := entry.Service.(HealthChecker); ok {
+			status = checker.Health(ctx)
+		}
+
+		report.Services[entry.Name] = status
+
+		// NOT_SERVING overrides UNKNOWN, UNKNOWN overrides SERVING
+		if status == HealthNotServing || (status == HealthUnknown && report.Status == HealthServing) {
+			report.Status = status
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,186 @@
+package smf4go
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/sdi4go"
+)
+
+// injectByType the inject tag value which resolve field by type instead of name, e.g.
+//
+//	Provider grpcservice.Provider    `inject:"*"` // the single service implement Provider
+//	Services []grpcservice.Service   `inject:"*"` // all services implement grpcservice.Service
+const injectByType = "*"
+
+// boundServices get services snapshot bound into injector
+func (builder *meshBuilderImpl) boundServices() ([]ServiceRegisterEntry, bool) {
+	services := builder.bound.Load().([]ServiceRegisterEntry)
+
+	return services, services != nil
+}
+
+// find find service by name in services without bound check, the scoped service instance
+// is created or get from scope
+func (builder *meshBuilderImpl) find(services []ServiceRegisterEntry, scope *serviceScope, name string, service interface{}) error {
+	for _, entry := range services {
+		if entry.Name != name {
+			continue
+		}
+
+		if factory, ok := entry.Service.(ScopedFactory); ok && factory.Scope() != ScopeSingleton {
+			instance, err := builder.instance(services, scope, name, factory)
+
+			if err != nil {
+				return err
+			}
+
+			return assign(name, instance, service)
+		}
+
+		break
+	}
+
+	if err := builder.injector.Load().(sdi4go.Injector).Create(name, service); err != nil {
+		if err == sdi4go.ErrNotFound {
+			return errors.Wrap(ErrNotFound, "service %s not found", name)
+		}
+
+		return errors.Wrap(ErrType, "service %s can't assign to %T", name, service)
+	}
+
+	return nil
+}
+
+// assignable get services which can be assigned to target type, the service named exclude
+// and scoped services are ignored
+func assignable(services []ServiceRegisterEntry, target reflect.Type, exclude string) []ServiceRegisterEntry {
+	var matched []ServiceRegisterEntry
+
+	for _, entry := range services {
+		if entry.Name == exclude || entry.Service == nil {
+			continue
+		}
+
+		if factory, ok := entry.Service.(ScopedFactory); ok && factory.Scope() != ScopeSingleton {
+			continue
+		}
+
+		if reflect.TypeOf(entry.Service).AssignableTo(target) {
+			matched = append(matched, entry)
+		}
+	}
+
+	return matched
+}
+
+func findByType(services []ServiceRegisterEntry, value reflect.Value, exclude string) error {
+	matched := assignable(services, value.Type(), exclude)
+
+	switch len(matched) {
+	case 0:
+		return errors.Wrap(ErrNotFound, "service of type %s not found", value.Type())
+	case 1:
+		value.Set(reflect.ValueOf(matched[0].Service))
+		return nil
+	default:
+		var names []string
+
+		for _, entry := range matched {
+			names = append(names, entry.Name)
+		}
+
+		return errors.Wrap(ErrAmbiguous, "services [%s] are all type of %s", strings.Join(names, ", "), value.Type())
+	}
+}
+
+func findAllByType(services []ServiceRegisterEntry, value reflect.Value, exclude string) {
+	matched := assignable(services, value.Type().Elem(), exclude)
+
+	slice := reflect.MakeSlice(value.Type(), 0, len(matched))
+
+	for _, entry := range matched {
+		slice = reflect.Append(slice, reflect.ValueOf(entry.Service))
+	}
+
+	value.Set(slice)
+}
+
+func (builder *meshBuilderImpl) FindServiceByType(service interface{}) error {
+	services, ok := builder.boundServices()
+
+	if !ok {
+		return errors.Wrap(ErrNotStarted, "find service by type %T error", service)
+	}
+
+	value := reflect.ValueOf(service)
+
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.Wrap(ErrType, "expect pointer, got %T", service)
+	}
+
+	return findByType(services, value.Elem(), "")
+}
+
+func (builder *meshBuilderImpl) FindServices(services interface{}) error {
+	bound, ok := builder.boundServices()
+
+	if !ok {
+		return errors.Wrap(ErrNotStarted, "find services by type %T error", services)
+	}
+
+	value := reflect.ValueOf(services)
+
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return errors.Wrap(ErrType, "expect slice pointer, got %T", services)
+	}
+
+	findAllByType(bound, value.Elem(), "")
+
+	return nil
+}
+
+// inject set service fields with `inject:"name"` tag by service name, and fields with `inject:"*"` tag by type
+func (builder *meshBuilderImpl) inject(entry ServiceRegisterEntry, services []ServiceRegisterEntry, scope *serviceScope) error {
+	value := reflect.ValueOf(entry.Service)
+
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	value = value.Elem()
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+
+		tag, ok := field.Tag.Lookup("inject")
+
+		if !ok {
+			continue
+		}
+
+		if field.PkgPath != "" {
+			return errors.Wrap(ErrType, "inject field %s must be public", field.Name)
+		}
+
+		if tag != injectByType {
+			if err := builder.find(services, scope, tag, value.Field(i).Addr().Interface()); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Slice {
+			findAllByType(services, value.Field(i), entry.Name)
+			continue
+		}
+
+		if err := findByType(services, value.Field(i), entry.Name); err != nil {
+			return errors.Wrap(err, "inject field %s error", field.Name)
+		}
+	}
+
+	return nil
+}
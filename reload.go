@@ -0,0 +1,78 @@
+package smf4go
+
+import (
+	"reflect"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+)
+
+// Reconfigurable service which can apply config changes without restart,
+// Reconfigure is called with the new service config when smf4go.service.<name> changed,
+// returns error to reject the new config
+type Reconfigurable interface {
+	Service
+	Reconfigure(config scf4go.Config) error
+}
+
+// serviceConfig get smf4go.service.<name> config subtree value
+func serviceConfig(config scf4go.Config, name string) interface{} {
+	var value interface{}
+
+	config.Get("smf4go", "service", name).Scan(&value)
+
+	return value
+}
+
+// Reload apply new config to the started mesh, only the Reconfigurable services whose config changed are
+// reconfigured, in creation order. if any service rejects the new config, the services reconfigured before it
+// are rolled back to the current config and the mesh keeps using the current config
+func (builder *meshBuilderImpl) Reload(config scf4go.Config) error {
+
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+
+	if !builder.started.Load().(bool) {
+		return errors.Wrap(ErrNotStarted, "reload config error")
+	}
+
+	var reconfigured []ServiceRegisterEntry
+
+	for _, entry := range builder.services {
+		if reflect.DeepEqual(serviceConfig(builder.config, entry.Name), serviceConfig(config, entry.Name)) {
+			continue
+		}
+
+		service, ok := entry.Service.(Reconfigurable)
+
+		if !ok {
+			builder.W("service {@service} config changed, restart to apply it", entry.Name)
+			continue
+		}
+
+		builder.I("reconfigure service {@service}", entry.Name)
+
+		if err := service.Reconfigure(config.SubConfig("smf4go", "service", entry.Name)); err != nil {
+			builder.E("service {@service} reject config, rollback: {@err}", entry.Name, err)
+			builder.rollback(reconfigured)
+			return errors.Wrap(err, "service %s reconfigure error", entry.Name)
+		}
+
+		reconfigured = append(reconfigured, entry)
+	}
+
+	builder.config = config
+
+	return nil
+}
+
+// rollback reconfigure services with current config in reverse order
+func (builder *meshBuilderImpl) rollback(reconfigured []ServiceRegisterEntry) {
+	for i := len(reconfigured) - 1; i >= 0; i-- {
+		entry := reconfigured[i]
+
+		if err := entry.Service.(Reconfigurable).Reconfigure(builder.config.SubConfig("smf4go", "service", entry.Name)); err != nil {
+			builder.E("rollback service {@service} config error: {@err}", entry.Name, err)
+		}
+	}
+}
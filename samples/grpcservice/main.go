@@ -34,7 +34,7 @@ func main() {
 
 	grpcService.Local("test.grpc.server", func(config scf4go.Config) (grpcservice.Service, error) {
 		return &grpcServer{}, nil
-	})
+	}, grpcservice.LocalWithPrototype((*grpcServer)(nil)))
 
 	grpcService.Remote("test.grpc.client", func(conn *grpc.ClientConn) (smf4go.Service, error) {
 		return &grpcClient{}, nil
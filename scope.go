@@ -0,0 +1,161 @@
+package smf4go
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/libs4go/errors"
+)
+
+// Scope service instance scope
+type Scope int
+
+// scope enum
+const (
+	ScopeSingleton Scope = iota // one instance shared by the mesh
+	ScopeTransient              // new instance for each FindService call or injection
+	ScopeContext                // one instance for each service scope context, see WithServiceScope
+)
+
+func (scope Scope) String() string {
+	switch scope {
+	case ScopeTransient:
+		return "transient"
+	case ScopeContext:
+		return "context"
+	default:
+		return "singleton"
+	}
+}
+
+// ScopedFactory service created by extension which create instances with scope other than singleton,
+// the mesh finds and injects the instances created by factory instead of the factory itself,
+// the scoped services can only be found by name
+type ScopedFactory interface {
+	Service
+	Scope() Scope
+	New() (Service, error)
+}
+
+// serviceScope context scoped instances
+type serviceScope struct {
+	sync.Mutex
+	instances map[string]Service
+	orders    []ServiceRegisterEntry
+}
+
+type serviceScopeKey struct{}
+
+// WithServiceScope create service scope context, the context scoped services found by FindServiceContext
+// with the context share the same instance. the release function close instances which implement
+// Stoppable or Closer in reverse creation order
+func WithServiceScope(ctx context.Context) (context.Context, func(ctx context.Context) error) {
+	scope := &serviceScope{
+		instances: make(map[string]Service),
+	}
+
+	release := func(ctx context.Context) error {
+		scope.Lock()
+		orders := scope.orders
+		scope.orders = nil
+		scope.instances = make(map[string]Service)
+		scope.Unlock()
+
+		var errs MultiError
+
+		for i := len(orders) - 1; i >= 0; i-- {
+			var err error
+
+			switch instance := orders[i].Service.(type) {
+			case Stoppable:
+				err = callWithContext(ctx, func() error { return instance.Stop(ctx) })
+			case Closer:
+				err = callWithContext(ctx, instance.Close)
+			}
+
+			if err != nil {
+				errs = append(errs, errors.Wrap(err, "release service %s error", orders[i].Name))
+			}
+		}
+
+		return errs.errorOrNil()
+	}
+
+	return context.WithValue(ctx, serviceScopeKey{}, scope), release
+}
+
+func scopeFromContext(ctx context.Context) *serviceScope {
+	scope, _ := ctx.Value(serviceScopeKey{}).(*serviceScope)
+	return scope
+}
+
+func (builder *meshBuilderImpl) FindServiceContext(ctx context.Context, name string, service interface{}) error {
+	services, ok := builder.boundServices()
+
+	if !ok {
+		return errors.Wrap(ErrNotStarted, "find service %s error", name)
+	}
+
+	return builder.find(services, scopeFromContext(ctx), name, service)
+}
+
+// instance get or create instance of scoped service
+func (builder *meshBuilderImpl) instance(services []ServiceRegisterEntry, scope *serviceScope, name string, factory ScopedFactory) (Service, error) {
+
+	if factory.Scope() == ScopeContext {
+		if scope == nil {
+			return nil, errors.Wrap(ErrScope, "context scoped service %s must be found with service scope context", name)
+		}
+
+		scope.Lock()
+		instance, ok := scope.instances[name]
+		scope.Unlock()
+
+		if ok {
+			return instance, nil
+		}
+	}
+
+	instance, err := factory.New()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "create %s scoped service %s error", factory.Scope(), name)
+	}
+
+	if err := builder.inject(ServiceRegisterEntry{Name: name, Service: instance}, services, scope); err != nil {
+		return nil, errors.Wrap(err, "service %s bind error", name)
+	}
+
+	if factory.Scope() == ScopeContext {
+		scope.Lock()
+		defer scope.Unlock()
+
+		// created by another goroutine with the same scope
+		if existing, ok := scope.instances[name]; ok {
+			return existing, nil
+		}
+
+		scope.instances[name] = instance
+		scope.orders = append(scope.orders, ServiceRegisterEntry{Name: name, Service: instance})
+	}
+
+	return instance, nil
+}
+
+// assign assign instance to service pointer
+func assign(name string, instance Service, service interface{}) error {
+	value := reflect.ValueOf(service)
+
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.Wrap(ErrType, "expect pointer, got %T", service)
+	}
+
+	if instance == nil || !reflect.TypeOf(instance).AssignableTo(value.Elem().Type()) {
+		return errors.Wrap(ErrType, "service %s can't assign to %T", name, service)
+	}
+
+	value.Elem().Set(reflect.ValueOf(instance))
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package grpcservice
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/resolver"
+)
+
+// grpc balancer names registered by grpcservice
+const (
+	weightedBalancer     = "smf4go_weighted"
+	leastRequestBalancer = "smf4go_least_request"
+)
+
+// balancers map Remote service balancer config name to grpc balancer name
+var balancers = map[string]string{
+	"pick_first":    "pick_first",
+	"round_robin":   roundrobin.Name,
+	"weighted":      weightedBalancer,
+	"least_request": leastRequestBalancer,
+}
+
+func init() {
+	balancer.Register(base.NewBalancerBuilder(weightedBalancer, &weightedPickerBuilder{}, base.Config{HealthCheck: true}))
+	balancer.Register(&leastRequestBalancerBuilder{})
+}
+
+// balancerServiceConfig create grpc service config json with balancer config name
+func balancerServiceConfig(name string) (string, error) {
+	grpcName, ok := balancers[name]
+
+	if !ok {
+		return "", errors.Wrap(smf4go.ErrNotFound, "balancer %s not found", name)
+	}
+
+	return fmt.Sprintf(`{"loadBalancingConfig":[{"%s":{}}]}`, grpcName), nil
+}
+
+// endpointWeight the endpoint weight carried by resolver.Address.Metadata
+type endpointWeight int
+
+func addressWeight(address resolver.Address) int {
+	if weight, ok := address.Metadata.(endpointWeight); ok && weight > 0 {
+		return int(weight)
+	}
+
+	return 1
+}
+
+type weightedPickerBuilder struct{}
+
+func (*weightedPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	picker := &weightedPicker{}
+
+	for subConn, subConnInfo := range info.ReadySCs {
+		picker.subConns = append(picker.subConns, &weightedSubConn{
+			subConn: subConn,
+			weight:  addressWeight(subConnInfo.Address),
+		})
+	}
+
+	return picker
+}
+
+type weightedSubConn struct {
+	subConn balancer.SubConn
+	weight  int
+	current int
+}
+
+// weightedPicker smooth weighted round-robin picker
+type weightedPicker struct {
+	mutex    sync.Mutex
+	subConns []*weightedSubConn
+}
+
+func (picker *weightedPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	picker.mutex.Lock()
+	defer picker.mutex.Unlock()
+
+	var selected *weightedSubConn
+
+	total := 0
+
+	for _, subConn := range picker.subConns {
+		subConn.current += subConn.weight
+		total += subConn.weight
+
+		if selected == nil || subConn.current > selected.current {
+			selected = subConn
+		}
+	}
+
+	selected.current -= total
+
+	return balancer.PickResult{SubConn: selected.subConn}, nil
+}
+
+// leastRequestBalancerBuilder create base balancer with picker builder for each ClientConn,
+// so the outstanding requests counters are owned by the connection
+type leastRequestBalancerBuilder struct{}
+
+func (*leastRequestBalancerBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pickerBuilder := newLeastRequestPickerBuilder()
+
+	return base.NewBalancerBuilder(leastRequestBalancer, pickerBuilder, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+func (*leastRequestBalancerBuilder) Name() string {
+	return leastRequestBalancer
+}
+
+// leastRequestPickerBuilder keep the SubConns outstanding requests across pickers, the picker
+// is rebuilt on each SubConn state change and the in-flight requests must not be forgotten
+type leastRequestPickerBuilder struct {
+	mutex    sync.Mutex
+	subConns map[balancer.SubConn]*leastRequestSubConn
+}
+
+func newLeastRequestPickerBuilder() *leastRequestPickerBuilder {
+	return &leastRequestPickerBuilder{
+		subConns: make(map[balancer.SubConn]*leastRequestSubConn),
+	}
+}
+
+func (builder *leastRequestPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+
+	// the removed SubConns are released, their pending requests Done only update the released counters
+	for subConn := range builder.subConns {
+		if _, ok := info.ReadySCs[subConn]; !ok {
+			delete(builder.subConns, subConn)
+		}
+	}
+
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	picker := &leastRequestPicker{}
+
+	for subConn := range info.ReadySCs {
+		counter, ok := builder.subConns[subConn]
+
+		if !ok {
+			counter = &leastRequestSubConn{subConn: subConn}
+			builder.subConns[subConn] = counter
+		}
+
+		picker.subConns = append(picker.subConns, counter)
+	}
+
+	return picker
+}
+
+type leastRequestSubConn struct {
+	subConn     balancer.SubConn
+	outstanding int64
+}
+
+// leastRequestPicker pick the SubConn with least outstanding requests,
+// SubConns with same outstanding requests are picked in round-robin order
+type leastRequestPicker struct {
+	mutex    sync.Mutex
+	subConns []*leastRequestSubConn
+	next     int
+}
+
+func (picker *leastRequestPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	picker.mutex.Lock()
+
+	var selected *leastRequestSubConn
+
+	for i := range picker.subConns {
+		subConn := picker.subConns[(picker.next+i)%len(picker.subConns)]
+
+		if selected == nil || atomic.LoadInt64(&subConn.outstanding) < atomic.LoadInt64(&selected.outstanding) {
+			selected = subConn
+		}
+	}
+
+	picker.next = (picker.next + 1) % len(picker.subConns)
+
+	picker.mutex.Unlock()
+
+	atomic.AddInt64(&selected.outstanding, 1)
+
+	return balancer.PickResult{
+		SubConn: selected.subConn,
+		Done: func(balancer.DoneInfo) {
+			atomic.AddInt64(&selected.outstanding, -1)
+		},
+	}, nil
+}
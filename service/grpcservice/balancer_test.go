@@ -0,0 +1,78 @@
+package grpcservice
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+type testSubConn struct {
+	name string
+}
+
+func (subConn *testSubConn) UpdateAddresses([]resolver.Address) {}
+
+func (subConn *testSubConn) Connect() {}
+
+func pickName(t *testing.T, picker balancer.Picker) (string, func(balancer.DoneInfo)) {
+	result, err := picker.Pick(balancer.PickInfo{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result.SubConn.(*testSubConn).name, result.Done
+}
+
+func TestWeightedPicker(t *testing.T) {
+	info := base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+		&testSubConn{name: "a"}: {Address: resolver.Address{Metadata: endpointWeight(5)}},
+		&testSubConn{name: "b"}: {Address: resolver.Address{}},
+		&testSubConn{name: "c"}: {Address: resolver.Address{Metadata: endpointWeight(1)}},
+	}}
+
+	picker := (&weightedPickerBuilder{}).Build(info)
+
+	picks := make(map[string]int)
+
+	for i := 0; i < 70; i++ {
+		name, _ := pickName(t, picker)
+		picks[name]++
+	}
+
+	if picks["a"] != 50 || picks["b"] != 10 || picks["c"] != 10 {
+		t.Fatalf("unexpected weighted picks %v", picks)
+	}
+
+	if _, err := (&weightedPickerBuilder{}).Build(base.PickerBuildInfo{}).Pick(balancer.PickInfo{}); err != balancer.ErrNoSubConnAvailable {
+		t.Fatalf("expect ErrNoSubConnAvailable, got %v", err)
+	}
+}
+
+func TestLeastRequestPicker(t *testing.T) {
+	info := base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+		&testSubConn{name: "a"}: {},
+		&testSubConn{name: "b"}: {},
+	}}
+
+	builder := newLeastRequestPickerBuilder()
+
+	first, done := pickName(t, builder.Build(info))
+
+	// the picker rebuilt on SubConn state change keeps the in-flight request
+	second, _ := pickName(t, builder.Build(info))
+
+	if first == second {
+		t.Fatalf("expect pick the SubConn without outstanding request, got %s twice", first)
+	}
+
+	done(balancer.DoneInfo{})
+
+	third, _ := pickName(t, builder.Build(info))
+
+	if third != first {
+		t.Fatalf("expect pick %s after its request done, got %s", first, third)
+	}
+}
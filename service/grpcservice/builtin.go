@@ -3,35 +3,191 @@ package grpcservice
 import (
 	"context"
 	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/libs4go/errors"
 	"github.com/libs4go/scf4go"
 	"github.com/libs4go/slf4go"
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc/credentials"
 )
 
+// builtinProvider the builtin Provider, the listener network is selected by config:
+//
+//	{ "network": "tcp", "laddr": ":8080" }
+//	{ "network": "unix", "laddr": "/var/run/app.sock", "mode": "0660", "cleanup": true }
+//	{ "network": "memory", "laddr": "app" }
+//
+// Connect select network by remote address form: unix:/path or unix:///path dial unix socket,
+// memory:name dial in-process memory listener, others dial tcp
 type builtinProvider struct {
 	slf4go.Logger
 	config   scf4go.Config
 	listener net.Listener
+	reloader *tlsReloader // server tls config, nil if tls not configured
+}
+
+// onceCloseListener wraps a net.Listener, protecting it from multiple Close calls.
+type onceCloseListener struct {
+	net.Listener
+	once     sync.Once
+	closeErr error
+}
+
+func (listener *onceCloseListener) Close() error {
+	listener.once.Do(func() {
+		listener.closeErr = listener.Listener.Close()
+	})
+
+	return listener.closeErr
 }
 
 func newBuiltinProvider(config scf4go.Config) (Provider, error) {
 
-	listener, err := net.Listen("tcp", config.Get("laddr").String(":8080"))
+	listener, err := listen(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reloader, err := newServerTLS(config)
 
 	if err != nil {
+		listener.Close()
 		return nil, err
 	}
 
 	return &builtinProvider{
 		Logger:   slf4go.Get("grpservice.default"),
-		listener: listener,
+		listener: &onceCloseListener{Listener: listener},
+		reloader: reloader,
 	}, nil
 }
 
+func listen(config scf4go.Config) (net.Listener, error) {
+	network := config.Get("network").String("tcp")
+
+	switch network {
+	case "tcp":
+		return net.Listen("tcp", config.Get("laddr").String(":8080"))
+	case "unix":
+		return listenUnix(config)
+	case "memory":
+		return listenMemory(config.Get("laddr").String(""))
+	default:
+		return nil, errors.Wrap(smf4go.ErrConfig, "unknown provider network %s", network)
+	}
+}
+
+func listenUnix(config scf4go.Config) (net.Listener, error) {
+	path := config.Get("laddr").String("")
+
+	if path == "" {
+		return nil, errors.Wrap(smf4go.ErrConfig, "unix socket path not configured")
+	}
+
+	if config.Get("cleanup").Bool(true) {
+		if err := removeStaleSocket(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if mode := config.Get("mode").String(""); mode != "" {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+
+		if err != nil {
+			listener.Close()
+			return nil, errors.Wrap(smf4go.ErrConfig, "invalid unix socket mode %s", mode)
+		}
+
+		if err := os.Chmod(path, os.FileMode(perm)); err != nil {
+			listener.Close()
+			return nil, errors.Wrap(err, "chmod unix socket %s error", path)
+		}
+	}
+
+	return listener, nil
+}
+
+// removeStaleSocket remove the unix socket file left by exited process, the socket is stale only if
+// dialing it is refused, the socket which a live process is listening on is kept
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+
+	if err == nil {
+		conn.Close()
+		return errors.Wrap(smf4go.ErrExists, "unix socket %s is in use", path)
+	}
+
+	if !isConnRefused(err) {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		return errors.Wrap(err, "remove stale unix socket %s error", path)
+	}
+
+	return nil
+}
+
+func isConnRefused(err error) bool {
+	opErr, ok := err.(*net.OpError)
+
+	if !ok {
+		return false
+	}
+
+	syscallErr, ok := opErr.Err.(*os.SyscallError)
+
+	return ok && syscallErr.Err == syscall.ECONNREFUSED
+}
+
 func (provider *builtinProvider) Listener() net.Listener {
 	return provider.listener
 }
 
 func (provider *builtinProvider) Connect(ctx context.Context, remote string) (net.Conn, error) {
-	return net.Dial("tcp", remote)
+	var dialer net.Dialer
+
+	switch {
+	case strings.HasPrefix(remote, "unix://"):
+		return dialer.DialContext(ctx, "unix", strings.TrimPrefix(remote, "unix://"))
+	case strings.HasPrefix(remote, "unix:"):
+		return dialer.DialContext(ctx, "unix", strings.TrimPrefix(remote, "unix:"))
+	case strings.HasPrefix(remote, "memory:"):
+		return dialMemory(ctx, strings.TrimPrefix(remote, "memory:"))
+	default:
+		return dialer.DialContext(ctx, "tcp", remote)
+	}
+}
+
+// Credentials implement SecureProvider, returns nil if tls not configured
+func (provider *builtinProvider) Credentials() credentials.TransportCredentials {
+	if provider.reloader == nil {
+		return nil
+	}
+
+	return newReloadingCredentials(provider.reloader)
+}
+
+// Close implement smf4go.Closer, release the provider listener
+func (provider *builtinProvider) Close() error {
+	return provider.listener.Close()
 }
@@ -0,0 +1,51 @@
+package grpcservice
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/smf4go"
+)
+
+func TestUnixSocketCleanup(t *testing.T) {
+	dir, cleanup := newTempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "grpc.sock")
+
+	config := newTestConfig(t, fmt.Sprintf(`{ "network": "unix", "laddr": %q }`, path))
+
+	live, err := net.Listen("unix", path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the socket of live process is kept
+	if _, err := newBuiltinProvider(config); !errors.Is(err, smf4go.ErrExists) {
+		t.Fatalf("expect ErrExists, got %v", err)
+	}
+
+	conn, err := net.Dial("unix", path)
+
+	if err != nil {
+		t.Fatalf("expect live socket kept, got %v", err)
+	}
+
+	conn.Close()
+
+	// the socket file is left as stale one
+	live.(*net.UnixListener).SetUnlinkOnClose(false)
+	live.Close()
+
+	provider, err := newBuiltinProvider(config)
+
+	if err != nil {
+		t.Fatalf("expect stale socket removed, got %v", err)
+	}
+
+	provider.(*builtinProvider).Close()
+}
@@ -2,7 +2,13 @@ package grpcservice
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/libs4go/errors"
@@ -11,6 +17,11 @@ import (
 	"github.com/libs4go/smf4go"
 	"github.com/libs4go/smf4go/service/localservice"
 	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 // Provider .
@@ -34,34 +45,105 @@ type ConnectorF func(conn *grpc.ClientConn) (smf4go.Service, error)
 // Register .
 type Register interface {
 	Client
-	Local(name string, creator CreatorF)
+	Local(name string, creator CreatorF, options ...LocalOption)
 	Remote(name string, connector ConnectorF)
+	// Intercept add interceptors for grpc server and all connections dialed by the register
+	Intercept(interceptors ...Interceptors)
+	// ServerAddr get the grpc server listener address, wait until the provider service found or ctx done
+	ServerAddr(ctx context.Context) (net.Addr, error)
 }
 
 // Client .
 type Client interface {
 	Dial(ctx context.Context, url string, dialOpts ...grpc.DialOption) (*grpc.ClientConn, error)
+	// State get the connectivity state of Remote service connection
+	State(name string) (connectivity.State, error)
+	// WatchState call f on each Remote service connection state change, until cancel called
+	WatchState(name string, f func(state connectivity.State)) (cancel func(), err error)
 }
 
 type registerImpl struct {
 	slf4go.Logger
+	name         string // register name
 	provider     string // provider serivce name
+	builtin      bool   // create builtin provider service
+	resolver     string // resolver service name
 	local        map[string]CreatorF
+	prototypes   map[string]reflect.Type // Local services types declared for validating
 	remote       map[string]ConnectorF
 	server       *grpc.Server
+	health       *health.Server // grpc.health.v1.Health service
 	config       scf4go.Config
 	servces      []Service
 	meshBulder   smf4go.MeshBuilder
 	localservice localservice.LocalService
+	closed       chan struct{}          // closed when grpc server stopped
+	stopOnce     sync.Once              // stop grpc server once
+	remotes      map[string]*remoteConn // remote services connections
+	remotesMutex sync.Mutex             // remotes mutex
+	routines     sync.WaitGroup         // background routines of a mesh run, joined by Teardown
+	interceptors interceptorChain       // server and client interceptors
+	intercepts   []Interceptors         // interceptors added by options and Intercept, kept across mesh runs
+	inproc       *inprocServer          // in-process endpoint of grpc server
+	hosted       map[string]bool        // grpc service names attached by Local services
+	state        int32                  // serve loop state
+
+	providerCached Provider      // provider service found by resolveProvider
+	providerMutex  sync.Mutex    // providerCached mutex
+	providerReady  chan struct{} // closed when provider found
+	resolverCached Resolver      // resolver service found by resolveResolver
+	resolverReady  chan struct{} // closed when resolver found
+	eager          []eagerConn   // eager Remote connections waited in End
 }
 
 // Option .
 type Option func(*registerImpl)
 
-// WithProvider .
+// localEntry Local service register entry
+type localEntry struct {
+	prototype reflect.Type // service type declared for validating
+}
+
+// LocalOption Local service register option
+type LocalOption func(*localEntry)
+
+// LocalWithPrototype declare the Local service type for MeshBuilder.Validate, e.g. LocalWithPrototype((*myService)(nil)),
+// the inject tags of service are checked without creating it
+func LocalWithPrototype(service Service) LocalOption {
+	return func(entry *localEntry) {
+		entry.prototype = reflect.TypeOf(service)
+	}
+}
+
+// WithProvider using Provider service with name, the service must be registered by caller
 func WithProvider(name string) Option {
 	return func(register *registerImpl) {
 		register.provider = name
+		register.builtin = false
+	}
+}
+
+// WithBuiltinProvider register builtin provider service with name, and using it as register's provider
+func WithBuiltinProvider(name string) Option {
+	return func(register *registerImpl) {
+		register.provider = name
+		register.builtin = true
+	}
+}
+
+// WithResolver resolve Remote services endpoints by Resolver service with name,
+// Remote service without remote address config will dial to smf4go:///<service name>
+func WithResolver(name string) Option {
+	return func(register *registerImpl) {
+		register.resolver = name
+	}
+}
+
+// WithInterceptors add interceptors, see Register.Intercept
+func WithInterceptors(interceptors ...Interceptors) Option {
+	return func(register *registerImpl) {
+		register.intercepts = append(register.intercepts, interceptors...)
+		register.interceptors.add(interceptors...)
 	}
 }
 
@@ -79,38 +161,73 @@ func WithLocalService(localservice localservice.LocalService) Option {
 	}
 }
 
-// New .
+// DefaultProvider the builtin provider service name of the first register created on a mesh builder
+const DefaultProvider = "grpcservice.default"
+
+// defaultProviders mesh builders which the default builtin provider is registered on,
+// the mesh builder is released when the register owning default provider torn down
+var defaultProviders = make(map[smf4go.MeshBuilder]bool)
+var defaultProvidersMutex sync.Mutex
+
+// New create grpc services register with name, which own one grpc server.
+// the register's Local services are attached to its own grpc server, and the grpc server config
+// is read from extension config keyed by register name:
+//
+//	{ "smf4go": { "extension": { "smf4go.extension.mxwservice.<name>": { ... } } } }
+//
+// the config keyed "smf4go.extension.mxwservice" is no longer read, move it to the key with register name.
+// the grpc reflection and channelz services are enabled by { "reflection": true, "channelz": true }.
+// if no provider option specified, the first register on a mesh builder using builtin provider
+// named DefaultProvider, others using builtin provider named grpcservice.<name>
 func New(name string, options ...Option) Register {
 
-	providerName := "grpcservice.default"
-
 	impl := &registerImpl{
 		Logger:     slf4go.Get("mxwservice"),
+		name:       name,
 		local:      make(map[string]CreatorF),
+		prototypes: make(map[string]reflect.Type),
 		remote:     make(map[string]ConnectorF),
-		provider:   providerName,
+		remotes:    make(map[string]*remoteConn),
 		meshBulder: smf4go.Builder(),
+		closed:     make(chan struct{}),
+
+		providerReady: make(chan struct{}),
+		resolverReady: make(chan struct{}),
 	}
 
 	for _, option := range options {
 		option(impl)
 	}
 
-	if impl.meshBulder == smf4go.Builder() {
-		impl.localservice = localservice.Get()
-	} else if impl.localservice == nil {
+	if impl.provider == "" {
+		defaultProvidersMutex.Lock()
+
+		if defaultProviders[impl.meshBulder] {
+			impl.provider = fmt.Sprintf("grpcservice.%s", name)
+		} else {
+			impl.provider = DefaultProvider
+			defaultProviders[impl.meshBulder] = true
+		}
+
+		defaultProvidersMutex.Unlock()
+
+		impl.builtin = true
+	}
+
+	// the registers without local service option share the mesh builder's one
+	if impl.localservice == nil {
 		impl.localservice = localservice.New(impl.meshBulder)
 	}
 
-	if impl.provider == providerName {
-		impl.localservice.Register(providerName, func(config scf4go.Config) (smf4go.Service, error) {
+	if impl.builtin {
+		impl.localservice.Register(impl.provider, func(config scf4go.Config) (smf4go.Service, error) {
 			return newBuiltinProvider(config)
 		})
 	}
 
 	impl.meshBulder.RegisterExtension(impl)
 
-	localservice.Register(name, func(config scf4go.Config) (smf4go.Service, error) {
+	impl.localservice.Register(name, func(config scf4go.Config) (smf4go.Service, error) {
 		impl.config = config
 		return impl, nil
 	})
@@ -120,56 +237,210 @@ func New(name string, options ...Option) Register {
 
 func (extension *registerImpl) Start() error {
 
+	policy, err := newRetryPolicy(extension.config)
+
+	if err != nil {
+		return errors.Wrap(err, "grpc serve retry config error")
+	}
+
+	if _, err := extension.resolveProvider(); err != nil {
+		return err
+	}
+
+	// register local services health status before serving, they are not serving until mesh started
+	extension.updateHealth()
+
+	extension.routines.Add(2)
+
 	go func() {
-		for {
-			if err := extension.server.Serve(extension); err != nil {
-				extension.E("grpc serve err {@err}", err)
-			}
+		defer extension.routines.Done()
+		extension.healthLoop()
+	}()
 
-			time.Sleep(extension.config.Get("backoff").Duration(time.Second * 5))
-		}
+	go extension.server.Serve(&insecureListener{memoryListener: extension.inproc.listener})
+
+	extension.inproc.serve(extension.hosted)
+
+	atomic.StoreInt32(&extension.state, serveRunning)
+
+	go func() {
+		defer extension.routines.Done()
+		extension.serve(policy)
+	}()
+
+	return nil
+}
+
+// Stop implement smf4go.Stoppable, graceful stop the grpc server,
+// if ctx done before all pending RPCs finished force stop it
+func (extension *registerImpl) Stop(ctx context.Context) error {
+
+	extension.stopOnce.Do(func() {
+		close(extension.closed)
+	})
+
+	atomic.StoreInt32(&extension.state, serveStopped)
+
+	if extension.server == nil {
+		return nil
+	}
+
+	extension.health.Shutdown()
+
+	extension.inproc.stop()
+
+	stopped := make(chan struct{})
+
+	go func() {
+		extension.server.GracefulStop()
+		close(stopped)
 	}()
 
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		extension.W("grpc server graceful stop timeout, force stop it")
+		extension.server.Stop()
+	}
+
 	return nil
 }
 
+// Teardown implement smf4go.Teardown, close remote services connections and release the register from mesh
+func (extension *registerImpl) Teardown(ctx context.Context) error {
+
+	removeMeshRegister(extension)
+
+	if extension.builtin && extension.provider == DefaultProvider {
+		defaultProvidersMutex.Lock()
+		delete(defaultProviders, extension.meshBulder)
+		defaultProvidersMutex.Unlock()
+	}
+
+	extension.remotesMutex.Lock()
+	defer extension.remotesMutex.Unlock()
+
+	var errs smf4go.MultiError
+
+	for _, remote := range extension.remotes {
+		// the connection returned as service by connector is closed by mesh already
+		if remote.conn.GetState() == connectivity.Shutdown {
+			continue
+		}
+
+		if err := remote.conn.Close(); err != nil {
+			errs = append(errs, errors.Wrap(err, "close grpc conn %s error", remote.conn.Target()))
+		}
+	}
+
+	extension.remotes = make(map[string]*remoteConn)
+
+	// the closed connections are shutdown, their monitors and resolvers exit
+	extension.routines.Wait()
+
+	if extension.inproc != nil {
+		if err := extension.inproc.conn.Close(); err != nil {
+			errs = append(errs, errors.Wrap(err, "close grpc conn %s error", extension.inproc.conn.Target()))
+		}
+	}
+
+	extension.reset()
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// reset reset the per-run state after torn down, so the register can be begun again when mesh restarted
+func (extension *registerImpl) reset() {
+	extension.closed = make(chan struct{})
+	extension.stopOnce = sync.Once{}
+
+	extension.providerMutex.Lock()
+	extension.providerCached = nil
+	extension.providerMutex.Unlock()
+
+	extension.providerReady = make(chan struct{})
+	extension.resolverCached = nil
+	extension.resolverReady = make(chan struct{})
+	extension.servces = nil
+	extension.hosted = nil
+	extension.eager = nil
+
+	atomic.StoreInt32(&extension.state, serveIdle)
+
+	// the interceptors provided by services are dropped with them
+	extension.interceptors.reset(extension.intercepts...)
+}
+
 func (extension *registerImpl) Name() string {
-	return "smf4go.extension.mxwservice"
+	return fmt.Sprintf("smf4go.extension.mxwservice.%s", extension.name)
+}
+
+// DependsOn implement smf4go.Dependent, begin after local service extension
+// which register the grpc provider service
+func (extension *registerImpl) DependsOn() []string {
+	return []string{localservice.ExtensionName}
 }
 
 // Accept waits for and returns the next connection to the listener.
 func (extension *registerImpl) Accept() (net.Conn, error) {
-	for {
-		provider := extension.getProvider()
-
-		if provider != nil {
-			return provider.Listener().Accept()
-		}
+	provider, err := extension.waitProvider(context.Background())
 
-		time.Sleep(time.Second)
+	if err != nil {
+		return nil, err
 	}
+
+	return provider.Listener().Accept()
 }
 
 // Close closes the listener.
 // Any blocked Accept operations will be unblocked and return errors.
 func (extension *registerImpl) Close() error {
-	provider := extension.getProvider()
-
-	if provider != nil {
+	if provider := extension.getProvider(); provider != nil {
 		return provider.Listener().Close()
 	}
 
 	return nil
 }
 
-func fakeLocalAddr() net.Addr {
-	localIP := net.ParseIP("127.0.0.1")
-	return &net.TCPAddr{IP: localIP, Port: 0}
+// Addr returns the listener's network address, returns the unspecified tcp address if the provider not found.
+func (extension *registerImpl) Addr() net.Addr {
+	if provider := extension.getProvider(); provider != nil {
+		return provider.Listener().Addr()
+	}
+
+	return &net.TCPAddr{}
 }
 
-// Addr returns the listener's network address.
-func (extension *registerImpl) Addr() net.Addr {
-	return fakeLocalAddr()
+// ServerAddrs get the grpc server listener addresses of registers created on mesh builder,
+// indexed by register name, the registers which provider service not found yet are ignored
+func ServerAddrs(builder smf4go.MeshBuilder) map[string]net.Addr {
+	meshRegistersMutex.Lock()
+	registers := meshRegisters[builder]
+	meshRegistersMutex.Unlock()
+
+	addrs := make(map[string]net.Addr)
+
+	for _, register := range registers {
+		if provider := register.getProvider(); provider != nil {
+			addrs[register.name] = provider.Listener().Addr()
+		}
+	}
+
+	return addrs
+}
+
+func (extension *registerImpl) ServerAddr(ctx context.Context) (net.Addr, error) {
+	provider, err := extension.waitProvider(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Listener().Addr(), nil
 }
 
 func (extension *registerImpl) Begin(config scf4go.Config, builder smf4go.MeshBuilder) error {
@@ -179,18 +450,118 @@ func (extension *registerImpl) Begin(config scf4go.Config, builder smf4go.MeshBu
 	}
 
 	for name := range extension.remote {
-		builder.RegisterService(extension.Name(), name)
+		if _, ok := extension.local[name]; !ok {
+			builder.RegisterService(extension.Name(), name)
+		}
+	}
+
+	options, err := serverOptions(config)
+
+	if err != nil {
+		return errors.Wrap(err, "grpc server config error")
+	}
+
+	options = append(options, grpc.Creds(&serverCredentials{register: extension}))
+
+	extension.server = grpc.NewServer(append(extension.interceptors.serverOptions(), options...)...)
+
+	extension.health = health.NewServer()
+
+	healthpb.RegisterHealthServer(extension.server, extension.health)
+
+	// debug services, the reflection service lists the Local services attached in End
+	if config.Get("reflection").Bool(false) {
+		reflection.Register(extension.server)
+	}
+
+	// channelz is turned on globally, the Remote services connections dialed after are tracked
+	if config.Get("channelz").Bool(false) {
+		channelz.RegisterChannelzServiceToServer(extension.server)
 	}
 
-	extension.server = grpc.NewServer()
+	extension.inproc, err = newInprocServer(extension.name)
+
+	if err != nil {
+		return errors.Wrap(err, "create grpc in-process endpoint error")
+	}
+
+	addMeshRegister(extension)
+
+	// the default provider released by Teardown is owned again when mesh restarted
+	if extension.builtin && extension.provider == DefaultProvider {
+		defaultProvidersMutex.Lock()
+		defaultProviders[extension.meshBulder] = true
+		defaultProvidersMutex.Unlock()
+	}
 
 	return nil
 }
 
+// Declare implement smf4go.Declarer, the Remote services without resolver require remote address config
+func (extension *registerImpl) Declare() []smf4go.ServiceDeclaration {
+	var declarations []smf4go.ServiceDeclaration
+
+	for _, name := range extension.localNames() {
+		declarations = append(declarations, smf4go.ServiceDeclaration{Name: name, Type: extension.prototypes[name]})
+	}
+
+	var remotes []string
+
+	for name := range extension.remote {
+		if _, ok := extension.local[name]; !ok {
+			remotes = append(remotes, name)
+		}
+	}
+
+	sort.Strings(remotes)
+
+	for _, name := range remotes {
+		declaration := smf4go.ServiceDeclaration{Name: name}
+
+		if extension.resolver == "" {
+			declaration.Required = []string{"remote"}
+		}
+
+		declarations = append(declarations, declaration)
+	}
+
+	return declarations
+}
+
+// serviceMode get the service is created as Local or Remote service, the service registered as both Local
+// and Remote select by config { "mode": "local" } or { "mode": "remote" }
+func (extension *registerImpl) serviceMode(serviceName string, config scf4go.Config) (string, error) {
+	_, local := extension.local[serviceName]
+	_, remote := extension.remote[serviceName]
+
+	if !local || !remote {
+		if local {
+			return "local", nil
+		}
+
+		return "remote", nil
+	}
+
+	switch mode := config.Get("mode").String(""); mode {
+	case "local", "remote":
+		return mode, nil
+	case "":
+		return "", errors.Wrap(smf4go.ErrAmbiguous, "service %s registered as both Local and Remote, select one by mode config", serviceName)
+	default:
+		return "", errors.Wrap(smf4go.ErrConfig, "service %s unknown mode %s", serviceName, mode)
+	}
+}
+
 func (extension *registerImpl) CreateSerivce(serviceName string, config scf4go.Config) (smf4go.Service, error) {
+	mode, err := extension.serviceMode(serviceName, config)
+
+	if err != nil {
+		return nil, err
+	}
+
 	f, ok := extension.local[serviceName]
 
-	if ok {
+	if ok && mode == "local" {
 		service, err := f(config)
 
 		if err != nil {
@@ -203,6 +574,8 @@ func (extension *registerImpl) CreateSerivce(serviceName string, config scf4go.C
 			extension.servces = append(extension.servces, grpcService)
 		}
 
+		extension.addInterceptors(service)
+
 		return service, nil
 
 	}
@@ -213,40 +586,155 @@ func (extension *registerImpl) CreateSerivce(serviceName string, config scf4go.C
 
 		remote := config.Get("remote").String("")
 
+		if remote == "" && extension.resolver != "" {
+			remote = fmt.Sprintf("%s:///%s", Scheme, serviceName)
+		}
+
 		extension.D("[{@serviceName}] grpc dial to {@remote}", serviceName, remote)
 
-		conn, err := extension.Dial(context.Background(), remote, grpc.WithInsecure())
+		reloader, err := newClientTLS(config)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "service %s tls config error", serviceName)
+		}
+
+		var dialOpts []grpc.DialOption
+
+		if reloader != nil {
+			dialOpts = append(dialOpts, grpc.WithTransportCredentials(newReloadingCredentials(reloader)))
+		} else {
+			dialOpts = append(dialOpts, grpc.WithInsecure())
+		}
+
+		options, err := dialOptions(config)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "service %s dial config error", serviceName)
+		}
+
+		dialOpts = append(dialOpts, options...)
+
+		// calls to grpc servers of this mesh are short-circuited in-process
+		// unless disabled by "inproc": false, the Remote services which rely on dial level
+		// PerRPCCredentials or client certificate identity should disable it
+		if config.Get("inproc").Bool(true) {
+			dialOpts = append(dialOpts, extension.inprocDialOptions()...)
+		}
+
+		if name := config.Get("balancer").String(""); name != "" {
+			serviceConfig, err := balancerServiceConfig(name)
+
+			if err != nil {
+				return nil, errors.Wrap(err, "service %s balancer config error", serviceName)
+			}
+
+			// the remote address without resolver scheme is resolved to single address
+			if extension.resolver == "" && !strings.Contains(remote, "://") {
+				extension.W("[{@serviceName}] balancer {@balancer} has no effect on single address {@remote}, configure resolver", serviceName, name, remote)
+			}
+
+			dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(serviceConfig))
+		}
+
+		conn, err := extension.Dial(context.Background(), remote, dialOpts...)
 
 		if err != nil {
 			return nil, err
 		}
 
-		return f2(conn)
+		if err := extension.connect(serviceName, conn, config); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		service, err := f2(conn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		extension.addInterceptors(service)
+
+		return service, nil
 	}
 
 	return nil, errors.Wrap(smf4go.ErrNotFound, "service %s not found", serviceName)
 }
 
+// getProvider get provider service found by resolveProvider, returns nil if not found yet
 func (extension *registerImpl) getProvider() Provider {
-	var provider Provider
-	smf4go.Builder().FindService(extension.provider, &provider)
+	extension.providerMutex.Lock()
+	defer extension.providerMutex.Unlock()
 
-	return provider
+	return extension.providerCached
 }
 
-func (extension *registerImpl) dialOption(ctx context.Context) grpc.DialOption {
-	return grpc.WithDialer(func(remote string, timeout time.Duration) (net.Conn, error) {
+// resolveProvider find provider service and wake up the provider waiters, the provider is cached
+// once found, so it is still available when the mesh is stopping
+func (extension *registerImpl) resolveProvider() (Provider, error) {
+	extension.providerMutex.Lock()
+	defer extension.providerMutex.Unlock()
 
-		provider := extension.getProvider()
+	if extension.providerCached != nil {
+		return extension.providerCached, nil
+	}
 
-		if provider == nil {
-			extension.D("grpc provider not exists ...")
-			return nil, errors.New("grpc provider not valid")
-		}
+	if err := extension.meshBulder.FindService(extension.provider, &extension.providerCached); err != nil {
+		return nil, errors.Wrap(err, "find grpc provider %s error", extension.provider)
+	}
+
+	close(extension.providerReady)
+
+	return extension.providerCached, nil
+}
+
+// waitProvider wait until provider service found, returns error if ctx done or register closed before that
+func (extension *registerImpl) waitProvider(ctx context.Context) (Provider, error) {
+	select {
+	case <-extension.providerReady:
+		return extension.getProvider(), nil
+	case <-extension.closed:
+		return nil, errors.Wrap(smf4go.ErrClosed, "grpc register %s closed", extension.name)
+	case <-ctx.Done():
+		return nil, errors.Wrap(smf4go.ErrTimeout, "wait grpc provider %s timeout", extension.provider)
+	}
+}
+
+// resolveResolver find resolver service and wake up the resolver waiters
+func (extension *registerImpl) resolveResolver() error {
+	if err := extension.meshBulder.FindService(extension.resolver, &extension.resolverCached); err != nil {
+		return errors.Wrap(err, "find grpc resolver %s error", extension.resolver)
+	}
+
+	close(extension.resolverReady)
+
+	return nil
+}
+
+// waitResolver wait until resolver service found, returns error if done or register closed before that
+func (extension *registerImpl) waitResolver(done <-chan struct{}) (Resolver, error) {
+	select {
+	case <-extension.resolverReady:
+		return extension.resolverCached, nil
+	case <-extension.closed:
+		return nil, errors.Wrap(smf4go.ErrClosed, "grpc register %s closed", extension.name)
+	case <-done:
+		return nil, errors.Wrap(smf4go.ErrClosed, "wait grpc resolver %s canceled", extension.resolver)
+	}
+}
+
+func (extension *registerImpl) dialOption(ctx context.Context) grpc.DialOption {
+	return grpc.WithDialer(func(remote string, timeout time.Duration) (net.Conn, error) {
 
 		subCtx, subCtxCancel := context.WithTimeout(ctx, timeout)
 		defer subCtxCancel()
 
+		provider, err := extension.waitProvider(subCtx)
+
+		if err != nil {
+			return nil, err
+		}
+
 		conn, err := provider.Connect(subCtx, remote)
 
 		if err != nil {
@@ -260,24 +748,71 @@ func (extension *registerImpl) dialOption(ctx context.Context) grpc.DialOption {
 
 func (extension *registerImpl) Dial(ctx context.Context, url string, dialOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
 
-	dialOpsPrepended := append([]grpc.DialOption{extension.dialOption(ctx)}, dialOpts...)
+	dialOpsPrepended := append([]grpc.DialOption{extension.dialOption(ctx)}, extension.interceptors.dialOptions()...)
+
+	if extension.resolver != "" {
+		dialOpsPrepended = append(dialOpsPrepended, grpc.WithResolvers(&resolverBuilder{register: extension}))
+	}
+
+	dialOpsPrepended = append(dialOpsPrepended, dialOpts...)
 
 	return grpc.DialContext(ctx, url, dialOpsPrepended...)
 }
 
 func (extension *registerImpl) End() error {
 
+	builtin := extension.server.GetServiceInfo()
+
 	for _, service := range extension.servces {
 		if err := service.GrpcHandler(extension.server); err != nil {
 			return err
 		}
 	}
 
+	extension.hosted = make(map[string]bool)
+
+	for name := range extension.server.GetServiceInfo() {
+		if _, ok := builtin[name]; !ok {
+			extension.hosted[name] = true
+		}
+	}
+
+	// the provider and resolver services can be found after mesh services bound
+	if _, err := extension.resolveProvider(); err != nil {
+		return err
+	}
+
+	if extension.resolver != "" {
+		if err := extension.resolveResolver(); err != nil {
+			return err
+		}
+	}
+
+	extension.waitEager()
+
 	return nil
 }
 
-func (extension *registerImpl) Local(name string, creator CreatorF) {
+func (extension *registerImpl) addInterceptors(service smf4go.Service) {
+	if provider, ok := service.(InterceptorProvider); ok {
+		extension.interceptors.add(provider.Interceptors()...)
+	}
+}
+
+func (extension *registerImpl) Intercept(interceptors ...Interceptors) {
+	extension.intercepts = append(extension.intercepts, interceptors...)
+	extension.interceptors.add(interceptors...)
+}
+
+func (extension *registerImpl) Local(name string, creator CreatorF, options ...LocalOption) {
+	entry := &localEntry{}
+
+	for _, option := range options {
+		option(entry)
+	}
+
 	extension.local[name] = creator
+	extension.prototypes[name] = entry.prototype
 }
 
 func (extension *registerImpl) Remote(name string, connector ConnectorF) {
@@ -0,0 +1,227 @@
+package grpcservice
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/smf4go"
+	"github.com/libs4go/smf4go/service/localservice"
+	"google.golang.org/grpc"
+)
+
+func TestMultipleRegisters(t *testing.T) {
+	builder := smf4go.NewMeshBuilder()
+
+	// the registers share the local service extension of mesh builder
+	a := New("a", WithMeshBuilder(builder)).(*registerImpl)
+	b := New("b", WithMeshBuilder(builder)).(*registerImpl)
+
+	if a.localservice != b.localservice || a.localservice != localservice.New(builder) {
+		t.Fatal("expect registers share local service of mesh builder")
+	}
+
+	if a.provider != DefaultProvider || b.provider != "grpcservice.b" {
+		t.Fatalf("unexpected providers %s %s", a.provider, b.provider)
+	}
+
+	// each register reads its own extension config
+	config := newTestConfig(t, fmt.Sprintf(`{ "smf4go": {
+		"extension": { "%s": { "reflection": true } },
+		"service": {
+			"%s": { "network": "tcp", "laddr": "127.0.0.1:0" },
+			"grpcservice.b": { "network": "tcp", "laddr": "127.0.0.1:0" }
+		}
+	} }`, a.Name(), DefaultProvider))
+
+	if err := builder.Start(config); err != nil {
+		t.Fatal(err)
+	}
+
+	addrs := ServerAddrs(builder)
+
+	if len(addrs) != 2 || addrs["a"].String() == addrs["b"].String() {
+		t.Fatalf("expect separate server addresses, got %v", addrs)
+	}
+
+	if a.server == b.server {
+		t.Fatal("expect separate grpc servers")
+	}
+
+	if _, ok := a.server.GetServiceInfo()["grpc.reflection.v1alpha.ServerReflection"]; !ok {
+		t.Fatal("expect reflection enabled on register a")
+	}
+
+	if _, ok := b.server.GetServiceInfo()["grpc.reflection.v1alpha.ServerReflection"]; ok {
+		t.Fatal("expect reflection disabled on register b")
+	}
+
+	if err := builder.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	meshRegistersMutex.Lock()
+	_, registered := meshRegisters[builder]
+	meshRegistersMutex.Unlock()
+
+	defaultProvidersMutex.Lock()
+	_, provided := defaultProviders[builder]
+	defaultProvidersMutex.Unlock()
+
+	if registered || provided {
+		t.Fatal("expect mesh builder released after registers torn down")
+	}
+}
+
+func TestInprocTarget(t *testing.T) {
+	builder := smf4go.NewMeshBuilder()
+
+	register := New("test.grpc", WithMeshBuilder(builder), WithLocalService(localservice.New(builder))).(*registerImpl)
+
+	register.Local("test.whoami", func(config scf4go.Config) (Service, error) {
+		return &whoamiService{}, nil
+	})
+
+	config := newTestConfig(t, fmt.Sprintf(`{ "smf4go": { "service": {
+		"%s": { "network": "tcp", "laddr": "127.0.0.1:0" }
+	} } }`, DefaultProvider))
+
+	if err := builder.Start(config); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	method := "/smf4go.test.WhoAmI/WhoAmI"
+
+	addr := register.Addr().String()
+
+	for _, target := range []string{addr, "passthrough:///" + addr, register.inproc.listener.Addr().String()} {
+		if register.inprocConn(target, method) != register.inproc.conn {
+			t.Fatalf("expect call to %s short-circuited", target)
+		}
+	}
+
+	// the service hosted by mesh is called through network if the target is not the mesh grpc server
+	if register.inprocConn("127.0.0.1:1", method) != nil {
+		t.Fatal("expect call to other server not short-circuited")
+	}
+
+	if register.inprocConn(addr, "/smf4go.test.Unknown/Call") != nil {
+		t.Fatal("expect unhosted service not short-circuited")
+	}
+}
+
+// injectedService Local grpc service which inject service by name
+type injectedService struct {
+	whoamiService
+	Upstream Client `inject:"test.upstream"`
+}
+
+func TestLocalPrototype(t *testing.T) {
+	builder := smf4go.NewMeshBuilder()
+
+	register := New("test.grpc", WithMeshBuilder(builder), WithLocalService(localservice.New(builder)))
+
+	register.Local("test.injected", func(config scf4go.Config) (Service, error) {
+		return &injectedService{}, nil
+	}, LocalWithPrototype((*injectedService)(nil)))
+
+	err := builder.Validate(newTestConfig(t, `{}`))
+
+	if errs, ok := err.(smf4go.MultiError); !ok || len(errs) != 1 || !errors.Is(errs[0], smf4go.ErrNotFound) {
+		t.Fatalf("expect inject service not registered, got %v", err)
+	}
+
+	register.Remote("test.upstream", func(conn *grpc.ClientConn) (smf4go.Service, error) {
+		return conn, nil
+	})
+
+	if err := builder.Validate(newTestConfig(t, `{ "smf4go": { "service": { "test.upstream": { "remote": "127.0.0.1:1" } } } }`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	builder := smf4go.NewMeshBuilder()
+
+	local := localservice.New(builder)
+
+	local.Register("test.resolver", StaticResolver)
+
+	register := New("test.grpc", WithMeshBuilder(builder), WithLocalService(local), WithResolver("test.resolver"))
+
+	register.Local("test.whoami", func(config scf4go.Config) (Service, error) {
+		return &whoamiService{}, nil
+	})
+
+	config := newTestConfig(t, fmt.Sprintf(`{ "smf4go": { "service": {
+		"%s": { "network": "tcp", "laddr": "127.0.0.1:0" },
+		"test.resolver": { "endpoints": {} }
+	} } }`, DefaultProvider))
+
+	for i := 0; i < 2; i++ {
+		if err := builder.Start(config); err != nil {
+			t.Fatalf("start %d error: %v", i, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+
+		addr, err := register.ServerAddr(ctx)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn, err := grpc.DialContext(ctx, addr.String(), grpc.WithInsecure())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := whoami(ctx, conn); err != nil {
+			t.Fatalf("call %d error: %v", i, err)
+		}
+
+		conn.Close()
+		cancel()
+
+		if err := builder.Stop(context.Background()); err != nil {
+			t.Fatalf("stop %d error: %v", i, err)
+		}
+	}
+}
+
+func TestListenerTarget(t *testing.T) {
+	unspecified := &net.TCPAddr{IP: net.IPv6unspecified, Port: 8080}
+
+	for target, expect := range map[string]bool{
+		"127.0.0.1:8080":            true,
+		"localhost:8080":            true,
+		"passthrough:///[::1]:8080": true,
+		"dns:///127.0.0.1:8080":     true,
+		"127.0.0.1:8081":            false,
+		"10.0.0.1:8080":             false,
+		"smf4go:///test.upstream":   false,
+		"unix:///var/run/grpc.sock": false,
+		"memory:inproc.test.grpc":   false,
+	} {
+		if isListenerTarget(target, unspecified) != expect {
+			t.Fatalf("target %s expect match %v", target, expect)
+		}
+	}
+
+	specified := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 8080}
+
+	if !isListenerTarget("10.0.0.1:8080", specified) || isListenerTarget("127.0.0.1:8080", specified) {
+		t.Fatal("expect specified host matched only")
+	}
+
+	if !isListenerTarget("unix:///var/run/grpc.sock", &net.UnixAddr{Net: "unix", Name: "/var/run/grpc.sock"}) {
+		t.Fatal("expect unix socket matched")
+	}
+}
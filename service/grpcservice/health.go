@@ -0,0 +1,76 @@
+package grpcservice
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/libs4go/smf4go"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var healthStatus = map[smf4go.HealthStatus]healthpb.HealthCheckResponse_ServingStatus{
+	smf4go.HealthUnknown:    healthpb.HealthCheckResponse_UNKNOWN,
+	smf4go.HealthServing:    healthpb.HealthCheckResponse_SERVING,
+	smf4go.HealthNotServing: healthpb.HealthCheckResponse_NOT_SERVING,
+}
+
+// localNames get sorted local service names
+func (extension *registerImpl) localNames() []string {
+	var names []string
+
+	for name := range extension.local {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// updateHealth sync mesh health report into grpc health server, the empty service name
+// report the mesh aggregate status
+func (extension *registerImpl) updateHealth() {
+	ctx, cancel := context.WithTimeout(context.Background(), extension.healthInterval())
+	defer cancel()
+
+	report := extension.meshBulder.Health(ctx)
+
+	extension.health.SetServingStatus("", healthStatus[report.Status])
+
+	for _, name := range extension.localNames() {
+		status, ok := report.Services[name]
+
+		if !ok {
+			status = smf4go.HealthNotServing
+		}
+
+		extension.health.SetServingStatus(name, healthStatus[status])
+	}
+}
+
+// MeshStarted implement smf4go.StartedObserver, publish the serving status once mesh started
+func (extension *registerImpl) MeshStarted() {
+	extension.updateHealth()
+}
+
+func (extension *registerImpl) healthInterval() time.Duration {
+	return extension.config.Get("healthInterval").Duration(time.Second * 5)
+}
+
+// healthLoop refresh grpc health server status until grpc server stopped
+func (extension *registerImpl) healthLoop() {
+
+	ticker := time.NewTicker(extension.healthInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-extension.closed:
+			return
+		case <-ticker.C:
+		}
+
+		extension.updateHealth()
+	}
+}
@@ -0,0 +1,223 @@
+package grpcservice
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc"
+)
+
+// meshRegisters registers begun on each mesh builder, the register is removed when torn down
+var meshRegisters = make(map[smf4go.MeshBuilder][]*registerImpl)
+var meshRegistersMutex sync.Mutex
+
+// addMeshRegister add register to mesh builder's registers
+func addMeshRegister(register *registerImpl) {
+	meshRegistersMutex.Lock()
+	defer meshRegistersMutex.Unlock()
+
+	meshRegisters[register.meshBulder] = append(meshRegisters[register.meshBulder], register)
+}
+
+// removeMeshRegister remove register from mesh builder's registers, the mesh builder without
+// registers is released
+func removeMeshRegister(register *registerImpl) {
+	meshRegistersMutex.Lock()
+	defer meshRegistersMutex.Unlock()
+
+	var registers []*registerImpl
+
+	for _, r := range meshRegisters[register.meshBulder] {
+		if r != register {
+			registers = append(registers, r)
+		}
+	}
+
+	if len(registers) == 0 {
+		delete(meshRegisters, register.meshBulder)
+		return
+	}
+
+	meshRegisters[register.meshBulder] = registers
+}
+
+// insecureConn the in-process endpoint connection, which is not handshaked by server credentials,
+// the remote address is the in-process endpoint address
+type insecureConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (conn *insecureConn) RemoteAddr() net.Addr {
+	return conn.addr
+}
+
+// insecureListener the in-process endpoint listener accepts insecureConn
+type insecureListener struct {
+	*memoryListener
+}
+
+func (listener *insecureListener) Accept() (net.Conn, error) {
+	conn, err := listener.memoryListener.Accept()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &insecureConn{Conn: conn, addr: listener.Addr()}, nil
+}
+
+// inprocServer the in-process endpoint of register's grpc server, Remote services calls whose grpc service
+// is hosted by a Local service of the same mesh are routed to it through in-memory pipes
+type inprocServer struct {
+	listener *memoryListener
+	conn     *grpc.ClientConn
+	mutex    sync.RWMutex
+	hosted   map[string]bool // grpc service names attached by Local services, nil if not serving
+}
+
+func newInprocServer(name string) (*inprocServer, error) {
+	listener := newMemoryListener("inproc."+name, nil)
+
+	// the connection is not intercepted, client interceptors are already called by the Remote connection
+	conn, err := grpc.DialContext(context.Background(), listener.addr.String(),
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, remote string) (net.Conn, error) {
+			return listener.dial(ctx)
+		}),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &inprocServer{
+		listener: listener,
+		conn:     conn,
+	}, nil
+}
+
+func (server *inprocServer) serve(hosted map[string]bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.hosted = hosted
+}
+
+func (server *inprocServer) stop() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.hosted = nil
+}
+
+func (server *inprocServer) hosts(service string) bool {
+	server.mutex.RLock()
+	defer server.mutex.RUnlock()
+
+	return server.hosted[service]
+}
+
+// isListenerTarget check if dial target is the listener address, the passthrough and dns schemes are ignored
+func isListenerTarget(target string, addr net.Addr) bool {
+	target = strings.TrimPrefix(strings.TrimPrefix(target, "passthrough:///"), "dns:///")
+
+	switch addr.Network() {
+	case "unix":
+		return target == "unix:"+addr.String() || target == "unix://"+addr.String()
+	case "tcp":
+		return isSameHostPort(target, addr.String())
+	default:
+		return target == addr.String()
+	}
+}
+
+// isSameHostPort check if the tcp addresses have same port and same host, the loopback
+// and unspecified hosts are same local host, e.g. 127.0.0.1:8080 is same as [::]:8080
+func isSameHostPort(target string, addr string) bool {
+	targetHost, targetPort, err := net.SplitHostPort(target)
+
+	if err != nil {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+
+	if err != nil || port != targetPort {
+		return false
+	}
+
+	return targetHost == host || isLocalHost(targetHost) && isLocalHost(host)
+}
+
+func isLocalHost(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// listens check if dial target is one of the register's grpc server addresses
+func (extension *registerImpl) listens(target string) bool {
+	if isListenerTarget(target, extension.inproc.listener.Addr()) {
+		return true
+	}
+
+	if provider := extension.getProvider(); provider != nil {
+		return isListenerTarget(target, provider.Listener().Addr())
+	}
+
+	return false
+}
+
+// inprocConn get the in-process connection of the register in the same mesh which listen on target
+// and serving method's grpc service, the targets resolved by resolver are not short-circuited
+func (extension *registerImpl) inprocConn(target string, method string) *grpc.ClientConn {
+	service := strings.TrimPrefix(method, "/")
+
+	if index := strings.LastIndex(service, "/"); index >= 0 {
+		service = service[:index]
+	}
+
+	meshRegistersMutex.Lock()
+	registers := meshRegisters[extension.meshBulder]
+	meshRegistersMutex.Unlock()
+
+	for _, register := range registers {
+		if register.inproc != nil && register.listens(target) && register.inproc.hosts(service) {
+			return register.inproc.conn
+		}
+	}
+
+	return nil
+}
+
+// inprocDialOptions create Remote connection interceptors, which are the innermost interceptors
+// of the connection, route calls to the in-process connection if the connection target is
+// a grpc server of this mesh which hosts the grpc service, the outgoing metadata and call options are passed through.
+// the calls skip the Remote connection transport, so the dial level PerRPCCredentials are not applied,
+// and the Local service gets no peer identity, the peer address is the in-process endpoint address
+func (extension *registerImpl) inprocDialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if conn := extension.inprocConn(cc.Target(), method); conn != nil {
+				return conn.Invoke(ctx, method, req, reply, opts...)
+			}
+
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			if conn := extension.inprocConn(cc.Target(), method); conn != nil {
+				return conn.NewStream(ctx, desc, method, opts...)
+			}
+
+			return streamer(ctx, desc, cc, method, opts...)
+		}),
+	}
+}
@@ -0,0 +1,174 @@
+package grpcservice
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Interceptors grpc interceptors group, nil interceptors are ignored
+type Interceptors struct {
+	Priority     int // interceptors with lower priority are called first, same priority keep the adding order
+	UnaryServer  grpc.UnaryServerInterceptor
+	StreamServer grpc.StreamServerInterceptor
+	UnaryClient  grpc.UnaryClientInterceptor
+	StreamClient grpc.StreamClientInterceptor
+}
+
+// InterceptorProvider Local or Remote service which contribute interceptors to the register created it,
+// the interceptors are added when the service created
+type InterceptorProvider interface {
+	Interceptors() []Interceptors
+}
+
+// interceptorChain the ordered interceptors chain, interceptors can be added after
+// grpc server created or connections dialed
+type interceptorChain struct {
+	mutex        sync.RWMutex
+	interceptors []Interceptors // unsorted interceptors
+	unaryServer  []grpc.UnaryServerInterceptor
+	streamServer []grpc.StreamServerInterceptor
+	unaryClient  []grpc.UnaryClientInterceptor
+	streamClient []grpc.StreamClientInterceptor
+}
+
+// reset replace the chain interceptors
+func (chain *interceptorChain) reset(interceptors ...Interceptors) {
+	chain.mutex.Lock()
+	chain.interceptors = nil
+	chain.mutex.Unlock()
+
+	chain.add(interceptors...)
+}
+
+func (chain *interceptorChain) add(interceptors ...Interceptors) {
+	chain.mutex.Lock()
+	defer chain.mutex.Unlock()
+
+	chain.interceptors = append(chain.interceptors, interceptors...)
+
+	sorted := append([]Interceptors(nil), chain.interceptors...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	chain.unaryServer = nil
+	chain.streamServer = nil
+	chain.unaryClient = nil
+	chain.streamClient = nil
+
+	for _, interceptors := range sorted {
+		if interceptors.UnaryServer != nil {
+			chain.unaryServer = append(chain.unaryServer, interceptors.UnaryServer)
+		}
+
+		if interceptors.StreamServer != nil {
+			chain.streamServer = append(chain.streamServer, interceptors.StreamServer)
+		}
+
+		if interceptors.UnaryClient != nil {
+			chain.unaryClient = append(chain.unaryClient, interceptors.UnaryClient)
+		}
+
+		if interceptors.StreamClient != nil {
+			chain.streamClient = append(chain.streamClient, interceptors.StreamClient)
+		}
+	}
+}
+
+func (chain *interceptorChain) unaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	chain.mutex.RLock()
+	interceptors := chain.unaryServer
+	chain.mutex.RUnlock()
+
+	var next func(i int) grpc.UnaryHandler
+
+	next = func(i int) grpc.UnaryHandler {
+		if i == len(interceptors) {
+			return handler
+		}
+
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptors[i](ctx, req, info, next(i+1))
+		}
+	}
+
+	return next(0)(ctx, req)
+}
+
+func (chain *interceptorChain) streamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	chain.mutex.RLock()
+	interceptors := chain.streamServer
+	chain.mutex.RUnlock()
+
+	var next func(i int) grpc.StreamHandler
+
+	next = func(i int) grpc.StreamHandler {
+		if i == len(interceptors) {
+			return handler
+		}
+
+		return func(srv interface{}, ss grpc.ServerStream) error {
+			return interceptors[i](srv, ss, info, next(i+1))
+		}
+	}
+
+	return next(0)(srv, ss)
+}
+
+func (chain *interceptorChain) unaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	chain.mutex.RLock()
+	interceptors := chain.unaryClient
+	chain.mutex.RUnlock()
+
+	var next func(i int) grpc.UnaryInvoker
+
+	next = func(i int) grpc.UnaryInvoker {
+		if i == len(interceptors) {
+			return invoker
+		}
+
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return interceptors[i](ctx, method, req, reply, cc, next(i+1), opts...)
+		}
+	}
+
+	return next(0)(ctx, method, req, reply, cc, opts...)
+}
+
+func (chain *interceptorChain) streamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	chain.mutex.RLock()
+	interceptors := chain.streamClient
+	chain.mutex.RUnlock()
+
+	var next func(i int) grpc.Streamer
+
+	next = func(i int) grpc.Streamer {
+		if i == len(interceptors) {
+			return streamer
+		}
+
+		return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return interceptors[i](ctx, desc, cc, method, next(i+1), opts...)
+		}
+	}
+
+	return next(0)(ctx, desc, cc, method, opts...)
+}
+
+func (chain *interceptorChain) serverOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(chain.unaryServerInterceptor),
+		grpc.StreamInterceptor(chain.streamServerInterceptor),
+	}
+}
+
+func (chain *interceptorChain) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(chain.unaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(chain.streamClientInterceptor),
+	}
+}
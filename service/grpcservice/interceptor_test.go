@@ -0,0 +1,39 @@
+package grpcservice
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func journalInterceptor(journal *[]string, name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		*journal = append(*journal, name)
+		return handler(ctx, req)
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	var journal []string
+
+	var chain interceptorChain
+
+	chain.add(Interceptors{Priority: 1, UnaryServer: journalInterceptor(&journal, "A")})
+	chain.add(Interceptors{Priority: 0, UnaryServer: journalInterceptor(&journal, "B")})
+	chain.add(Interceptors{Priority: 1, UnaryServer: journalInterceptor(&journal, "C")}, Interceptors{})
+
+	_, err := chain.unaryServerInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		journal = append(journal, "handler")
+		return nil, nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(journal) != "[B A C handler]" {
+		t.Fatalf("unexpected interceptors order %v", journal)
+	}
+}
@@ -0,0 +1,121 @@
+package grpcservice
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/smf4go"
+)
+
+// memoryAddr in-process memory listener address
+type memoryAddr string
+
+func (addr memoryAddr) Network() string {
+	return "memory"
+}
+
+func (addr memoryAddr) String() string {
+	return "memory:" + string(addr)
+}
+
+// memoryListener in-process listener, the connections are in-memory pipes
+type memoryListener struct {
+	addr      memoryAddr
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+	onClose   func()
+}
+
+func newMemoryListener(name string, onClose func()) *memoryListener {
+	return &memoryListener{
+		addr:    memoryAddr(name),
+		conns:   make(chan net.Conn),
+		closed:  make(chan struct{}),
+		onClose: onClose,
+	}
+}
+
+func (listener *memoryListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-listener.conns:
+		return conn, nil
+	case <-listener.closed:
+		return nil, errors.Wrap(smf4go.ErrClosed, "memory listener %s closed", listener.addr)
+	}
+}
+
+func (listener *memoryListener) Close() error {
+	listener.closeOnce.Do(func() {
+		close(listener.closed)
+
+		if listener.onClose != nil {
+			listener.onClose()
+		}
+	})
+
+	return nil
+}
+
+func (listener *memoryListener) Addr() net.Addr {
+	return listener.addr
+}
+
+// dial create in-memory pipe and hand the server side to Accept
+func (listener *memoryListener) dial(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case listener.conns <- server:
+		return client, nil
+	case <-listener.closed:
+	case <-ctx.Done():
+	}
+
+	client.Close()
+	server.Close()
+
+	return nil, errors.Wrap(smf4go.ErrNotFound, "memory listener %s not accept connection", listener.addr)
+}
+
+// memoryListeners in-process memory listeners indexed by name
+var memoryListeners = make(map[string]*memoryListener)
+var memoryListenersMutex sync.Mutex
+
+func listenMemory(name string) (net.Listener, error) {
+	memoryListenersMutex.Lock()
+	defer memoryListenersMutex.Unlock()
+
+	if name == "" {
+		return nil, errors.Wrap(smf4go.ErrConfig, "memory listener name not configured")
+	}
+
+	if _, ok := memoryListeners[name]; ok {
+		return nil, errors.Wrap(smf4go.ErrExists, "memory listener %s exists", name)
+	}
+
+	listener := newMemoryListener(name, func() {
+		memoryListenersMutex.Lock()
+		defer memoryListenersMutex.Unlock()
+
+		delete(memoryListeners, name)
+	})
+
+	memoryListeners[name] = listener
+
+	return listener, nil
+}
+
+func dialMemory(ctx context.Context, name string) (net.Conn, error) {
+	memoryListenersMutex.Lock()
+	listener, ok := memoryListeners[name]
+	memoryListenersMutex.Unlock()
+
+	if !ok {
+		return nil, errors.Wrap(smf4go.ErrNotFound, "memory listener %s not found", name)
+	}
+
+	return listener.dial(ctx)
+}
@@ -0,0 +1,258 @@
+package grpcservice
+
+import (
+	"strings"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // register gzip compressor
+	"google.golang.org/grpc/keepalive"
+)
+
+// getDuration get duration config value, returns error if the value is not valid duration string
+func getDuration(config scf4go.Config, path ...string) (time.Duration, error) {
+	value := config.Get(path...).String("")
+
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil || duration < 0 {
+		return 0, errors.Wrap(smf4go.ErrConfig, "%v invalid duration %s", path, value)
+	}
+
+	return duration, nil
+}
+
+// getSize get non-negative int config value
+func getSize(config scf4go.Config, path ...string) (int, error) {
+	value := config.Get(path...).Int(0)
+
+	if value < 0 {
+		return 0, errors.Wrap(smf4go.ErrConfig, "%v invalid size %d", path, value)
+	}
+
+	return value, nil
+}
+
+func checkCompressor(name string) error {
+	if name != "" && encoding.GetCompressor(name) == nil {
+		return errors.Wrap(smf4go.ErrConfig, "compressor %s not found", name)
+	}
+
+	return nil
+}
+
+// serverOptions create grpc.ServerOption from extension config, e.g.
+//
+//	{
+//	  "maxRecvMsgSize": 4194304, "maxSendMsgSize": 4194304, "maxConcurrentStreams": 100,
+//	  "connectionTimeout": "120s",
+//	  "keepalive": { "time": "2h", "timeout": "20s", "maxConnectionIdle": "0s", "maxConnectionAge": "0s", "maxConnectionAgeGrace": "0s" },
+//	  "enforcement": { "minTime": "5m", "permitWithoutStream": false }
+//	}
+//
+// the server decompress requests with registered compressors and compress responses with the request compressor,
+// the compression is selected by Remote services config
+func serverOptions(config scf4go.Config) ([]grpc.ServerOption, error) {
+	var options []grpc.ServerOption
+
+	if size, err := getSize(config, "maxRecvMsgSize"); err != nil {
+		return nil, err
+	} else if size > 0 {
+		options = append(options, grpc.MaxRecvMsgSize(size))
+	}
+
+	if size, err := getSize(config, "maxSendMsgSize"); err != nil {
+		return nil, err
+	} else if size > 0 {
+		options = append(options, grpc.MaxSendMsgSize(size))
+	}
+
+	if size, err := getSize(config, "maxConcurrentStreams"); err != nil {
+		return nil, err
+	} else if size > 0 {
+		options = append(options, grpc.MaxConcurrentStreams(uint32(size)))
+	}
+
+	if timeout, err := getDuration(config, "connectionTimeout"); err != nil {
+		return nil, err
+	} else if timeout > 0 {
+		options = append(options, grpc.ConnectionTimeout(timeout))
+	}
+
+	var params keepalive.ServerParameters
+
+	for path, value := range map[string]*time.Duration{
+		"time":                  &params.Time,
+		"timeout":               &params.Timeout,
+		"maxConnectionIdle":     &params.MaxConnectionIdle,
+		"maxConnectionAge":      &params.MaxConnectionAge,
+		"maxConnectionAgeGrace": &params.MaxConnectionAgeGrace,
+	} {
+		duration, err := getDuration(config, "keepalive", path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		*value = duration
+	}
+
+	if params != (keepalive.ServerParameters{}) {
+		options = append(options, grpc.KeepaliveParams(params))
+	}
+
+	minTime, err := getDuration(config, "enforcement", "minTime")
+
+	if err != nil {
+		return nil, err
+	}
+
+	permitWithoutStream := config.Get("enforcement", "permitWithoutStream").Bool(false)
+
+	if minTime > 0 || permitWithoutStream {
+		options = append(options, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             minTime,
+			PermitWithoutStream: permitWithoutStream,
+		}))
+	}
+
+	return options, nil
+}
+
+// dialOptions create grpc.DialOption from Remote service config, e.g.
+//
+//	{
+//	  "maxCallRecvMsgSize": 4194304, "maxCallSendMsgSize": 4194304,
+//	  "connectTimeout": "20s", "compression": "gzip",
+//	  "backoff": { "baseDelay": "1s", "multiplier": 1.6, "jitter": 0.2, "maxDelay": "120s" },
+//	  "keepalive": { "time": "1m", "timeout": "20s", "permitWithoutStream": false }
+//	}
+func dialOptions(config scf4go.Config) ([]grpc.DialOption, error) {
+	var options []grpc.DialOption
+
+	var callOptions []grpc.CallOption
+
+	if size, err := getSize(config, "maxCallRecvMsgSize"); err != nil {
+		return nil, err
+	} else if size > 0 {
+		callOptions = append(callOptions, grpc.MaxCallRecvMsgSize(size))
+	}
+
+	if size, err := getSize(config, "maxCallSendMsgSize"); err != nil {
+		return nil, err
+	} else if size > 0 {
+		callOptions = append(callOptions, grpc.MaxCallSendMsgSize(size))
+	}
+
+	compression := config.Get("compression").String("")
+
+	if err := checkCompressor(compression); err != nil {
+		return nil, err
+	}
+
+	if compression != "" {
+		callOptions = append(callOptions, grpc.UseCompressor(compression))
+	}
+
+	if len(callOptions) > 0 {
+		options = append(options, grpc.WithDefaultCallOptions(callOptions...))
+	}
+
+	connectParams, err := connectParams(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if connectParams != nil {
+		options = append(options, grpc.WithConnectParams(*connectParams))
+	}
+
+	var params keepalive.ClientParameters
+
+	for path, value := range map[string]*time.Duration{
+		"time":    &params.Time,
+		"timeout": &params.Timeout,
+	} {
+		duration, err := getDuration(config, "keepalive", path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		*value = duration
+	}
+
+	params.PermitWithoutStream = config.Get("keepalive", "permitWithoutStream").Bool(false)
+
+	if params != (keepalive.ClientParameters{}) {
+		options = append(options, grpc.WithKeepaliveParams(params))
+	}
+
+	return options, nil
+}
+
+// connectParams create reconnecting params from connectTimeout and backoff config,
+// the unspecified values are grpc defaults, returns nil if neither configured
+func connectParams(config scf4go.Config) (*grpc.ConnectParams, error) {
+	params := &grpc.ConnectParams{
+		Backoff:           backoff.DefaultConfig,
+		MinConnectTimeout: time.Second * 20,
+	}
+
+	configured := false
+
+	for path, value := range map[string]*time.Duration{
+		"connectTimeout":    &params.MinConnectTimeout,
+		"backoff.baseDelay": &params.Backoff.BaseDelay,
+		"backoff.maxDelay":  &params.Backoff.MaxDelay,
+	} {
+		duration, err := getDuration(config, strings.Split(path, ".")...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if duration > 0 {
+			*value = duration
+			configured = true
+		}
+	}
+
+	if multiplier := config.Get("backoff", "multiplier").Float64(0); multiplier != 0 {
+		if multiplier < 1 {
+			return nil, errors.Wrap(smf4go.ErrConfig, "invalid backoff multiplier %v", multiplier)
+		}
+
+		params.Backoff.Multiplier = multiplier
+		configured = true
+	}
+
+	if jitter := config.Get("backoff", "jitter").Float64(-1); jitter != -1 {
+		if jitter < 0 || jitter > 1 {
+			return nil, errors.Wrap(smf4go.ErrConfig, "invalid backoff jitter %v", jitter)
+		}
+
+		params.Backoff.Jitter = jitter
+		configured = true
+	}
+
+	if params.Backoff.MaxDelay < params.Backoff.BaseDelay {
+		return nil, errors.Wrap(smf4go.ErrConfig, "backoff maxDelay less than baseDelay")
+	}
+
+	if !configured {
+		return nil, nil
+	}
+
+	return params, nil
+}
@@ -0,0 +1,90 @@
+package grpcservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/smf4go"
+)
+
+func TestServerOptions(t *testing.T) {
+	options, err := serverOptions(newTestConfig(t, `{
+		"maxRecvMsgSize": 1024, "maxSendMsgSize": 1024, "maxConcurrentStreams": 10, "connectionTimeout": "1s",
+		"keepalive": { "time": "1m" }, "enforcement": { "minTime": "1s" }
+	}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(options) != 6 {
+		t.Fatalf("expect 6 server options, got %d", len(options))
+	}
+
+	for _, data := range []string{
+		`{ "maxRecvMsgSize": -1 }`,
+		`{ "connectionTimeout": "forever" }`,
+		`{ "keepalive": { "maxConnectionAge": "-1s" } }`,
+		`{ "enforcement": { "minTime": "soon" } }`,
+	} {
+		if _, err := serverOptions(newTestConfig(t, data)); !errors.Is(err, smf4go.ErrConfig) {
+			t.Fatalf("expect ErrConfig for %s, got %v", data, err)
+		}
+	}
+}
+
+func TestDialOptions(t *testing.T) {
+	options, err := dialOptions(newTestConfig(t, `{
+		"maxCallRecvMsgSize": 1024, "compression": "gzip", "connectTimeout": "1s", "keepalive": { "time": "1m" }
+	}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// default call options, connect params and keepalive
+	if len(options) != 3 {
+		t.Fatalf("expect 3 dial options, got %d", len(options))
+	}
+
+	for _, data := range []string{
+		`{ "maxCallSendMsgSize": -1 }`,
+		`{ "compression": "unknown" }`,
+		`{ "keepalive": { "timeout": "never" } }`,
+		`{ "backoff": { "baseDelay": "later" } }`,
+	} {
+		if _, err := dialOptions(newTestConfig(t, data)); !errors.Is(err, smf4go.ErrConfig) {
+			t.Fatalf("expect ErrConfig for %s, got %v", data, err)
+		}
+	}
+}
+
+func TestConnectParams(t *testing.T) {
+	params, err := connectParams(newTestConfig(t, `{}`))
+
+	if err != nil || params != nil {
+		t.Fatalf("expect no connect params, got %v %v", params, err)
+	}
+
+	params, err = connectParams(newTestConfig(t, `{ "connectTimeout": "5s", "backoff": { "baseDelay": "100ms", "multiplier": 2, "jitter": 0 } }`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if params.MinConnectTimeout != time.Second*5 || params.Backoff.BaseDelay != time.Millisecond*100 ||
+		params.Backoff.Multiplier != 2 || params.Backoff.Jitter != 0 || params.Backoff.MaxDelay != time.Second*120 {
+		t.Fatalf("unexpected connect params %+v", params)
+	}
+
+	for _, data := range []string{
+		`{ "backoff": { "multiplier": 0.5 } }`,
+		`{ "backoff": { "jitter": 2 } }`,
+		`{ "backoff": { "baseDelay": "10s", "maxDelay": "1s" } }`,
+	} {
+		if _, err := connectParams(newTestConfig(t, data)); !errors.Is(err, smf4go.ErrConfig) {
+			t.Fatalf("expect ErrConfig for %s, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package grpcservice
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Remote service connect mode
+const (
+	ConnectLazy  = "lazy"  // connect in background, mesh start does not wait for the connection
+	ConnectEager = "eager" // mesh start wait until the connection ready or waitForReady timeout
+)
+
+// remoteConn Remote service connection and it's state watchers
+type remoteConn struct {
+	sync.Mutex
+	name     string
+	conn     *grpc.ClientConn
+	next     int
+	watchers map[int]func(connectivity.State)
+}
+
+func newRemoteConn(name string, conn *grpc.ClientConn) *remoteConn {
+	return &remoteConn{
+		name:     name,
+		conn:     conn,
+		watchers: make(map[int]func(connectivity.State)),
+	}
+}
+
+func (remote *remoteConn) watch(f func(connectivity.State)) func() {
+	remote.Lock()
+	defer remote.Unlock()
+
+	id := remote.next
+	remote.next++
+
+	remote.watchers[id] = f
+
+	return func() {
+		remote.Lock()
+		defer remote.Unlock()
+
+		delete(remote.watchers, id)
+	}
+}
+
+func (remote *remoteConn) notify(state connectivity.State) {
+	remote.Lock()
+
+	var watchers []func(connectivity.State)
+
+	for _, f := range remote.watchers {
+		watchers = append(watchers, f)
+	}
+
+	remote.Unlock()
+
+	for _, f := range watchers {
+		f(state)
+	}
+}
+
+// monitor log and notify connection state changes until the connection closed,
+// the state changes are not logged once closed channel closed
+func (remote *remoteConn) monitor(logger slf4go.Logger, closed <-chan struct{}) {
+	state := remote.conn.GetState()
+
+	for {
+		select {
+		case <-closed:
+		default:
+			if state == connectivity.TransientFailure {
+				logger.W("[{@serviceName}] grpc connection state {@state}", remote.name, state.String())
+			} else {
+				logger.D("[{@serviceName}] grpc connection state {@state}", remote.name, state.String())
+			}
+		}
+
+		remote.notify(state)
+
+		if state == connectivity.Shutdown {
+			return
+		}
+
+		if !remote.conn.WaitForStateChange(context.Background(), state) {
+			return
+		}
+
+		state = remote.conn.GetState()
+	}
+}
+
+// waitForReady wait until the connection ready, returns false if ctx done before that
+func (remote *remoteConn) waitForReady(ctx context.Context) bool {
+	for {
+		state := remote.conn.GetState()
+
+		if state == connectivity.Ready {
+			return true
+		}
+
+		if !remote.conn.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+}
+
+// eagerConn eager Remote connection and its waitForReady timeout
+type eagerConn struct {
+	remote  *remoteConn
+	timeout time.Duration
+}
+
+// connect start monitoring Remote service connection, if connect mode is eager the connection
+// is waited for ready in End after provider and resolver found, the mesh start is not failed if
+// wait timeout, the connection keep reconnecting in background
+//
+//	{ "connect": "eager", "waitForReady": "10s" }
+func (extension *registerImpl) connect(name string, conn *grpc.ClientConn, config scf4go.Config) error {
+
+	mode := config.Get("connect").String(ConnectLazy)
+
+	if mode != ConnectLazy && mode != ConnectEager {
+		return errors.Wrap(smf4go.ErrConfig, "service %s unknown connect mode %s", name, mode)
+	}
+
+	timeout, err := getDuration(config, "waitForReady")
+
+	if err != nil {
+		return err
+	}
+
+	if timeout == 0 {
+		timeout = time.Second * 10
+	}
+
+	remote := newRemoteConn(name, conn)
+
+	extension.remotesMutex.Lock()
+	extension.remotes[name] = remote
+	extension.remotesMutex.Unlock()
+
+	extension.routines.Add(1)
+
+	go func() {
+		defer extension.routines.Done()
+		remote.monitor(extension.Logger, extension.closed)
+	}()
+
+	if mode == ConnectEager {
+		extension.eager = append(extension.eager, eagerConn{remote: remote, timeout: timeout})
+	}
+
+	return nil
+}
+
+// waitEager wait for eager Remote connections ready concurrently
+func (extension *registerImpl) waitEager() {
+	var wg sync.WaitGroup
+
+	for _, eager := range extension.eager {
+		wg.Add(1)
+
+		go func(eager eagerConn) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), eager.timeout)
+			defer cancel()
+
+			if !eager.remote.waitForReady(ctx) {
+				extension.W("[{@serviceName}] grpc connection not ready in {@timeout}, keep connecting in background",
+					eager.remote.name, eager.timeout.String())
+			}
+		}(eager)
+	}
+
+	wg.Wait()
+
+	extension.eager = nil
+}
+
+func (extension *registerImpl) getRemote(name string) (*remoteConn, error) {
+	extension.remotesMutex.Lock()
+	defer extension.remotesMutex.Unlock()
+
+	remote, ok := extension.remotes[name]
+
+	if !ok {
+		return nil, errors.Wrap(smf4go.ErrNotFound, "remote service %s not found", name)
+	}
+
+	return remote, nil
+}
+
+func (extension *registerImpl) State(name string) (connectivity.State, error) {
+	remote, err := extension.getRemote(name)
+
+	if err != nil {
+		return connectivity.Shutdown, err
+	}
+
+	return remote.conn.GetState(), nil
+}
+
+func (extension *registerImpl) WatchState(name string, f func(state connectivity.State)) (func(), error) {
+	remote, err := extension.getRemote(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return remote.watch(f), nil
+}
@@ -0,0 +1,358 @@
+package grpcservice
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme the grpc target scheme resolved by Register's Resolver, e.g. smf4go:///payments
+const Scheme = "smf4go"
+
+// Endpoint service endpoint, the json form is address string or
+// object { "addr": "127.0.0.1:8080", "weight": 2 }
+type Endpoint struct {
+	Addr   string `json:"addr"`   // endpoint address, dialed by Provider.Connect
+	Weight int    `json:"weight"` // endpoint weight used by weighted balancer, default 1
+}
+
+// UnmarshalJSON implement json.Unmarshaler
+func (endpoint *Endpoint) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &endpoint.Addr); err == nil {
+		return nil
+	}
+
+	type endpointObject Endpoint
+
+	return json.Unmarshal(data, (*endpointObject)(endpoint))
+}
+
+// Resolver map logical smf4go service name to a set of endpoints
+type Resolver interface {
+	// Watch watch service endpoints changes, the current endpoints must be delivered
+	// by update function as soon as possible. call cancel function to stop watching
+	Watch(name string, update func(endpoints []Endpoint)) (cancel func(), err error)
+}
+
+// watchers the resolver watchers group
+type watchers struct {
+	sync.Mutex
+	next     int
+	watchers map[string]map[int]func([]Endpoint)
+}
+
+func (group *watchers) add(name string, update func([]Endpoint)) func() {
+	group.Lock()
+	defer group.Unlock()
+
+	if group.watchers == nil {
+		group.watchers = make(map[string]map[int]func([]Endpoint))
+	}
+
+	if group.watchers[name] == nil {
+		group.watchers[name] = make(map[int]func([]Endpoint))
+	}
+
+	id := group.next
+	group.next++
+
+	group.watchers[name][id] = update
+
+	return func() {
+		group.Lock()
+		defer group.Unlock()
+
+		delete(group.watchers[name], id)
+	}
+}
+
+func (group *watchers) notify(name string, endpoints []Endpoint) {
+	group.Lock()
+
+	var updates []func([]Endpoint)
+
+	for _, update := range group.watchers[name] {
+		updates = append(updates, update)
+	}
+
+	group.Unlock()
+
+	for _, update := range updates {
+		update(endpoints)
+	}
+}
+
+func (group *watchers) names() []string {
+	group.Lock()
+	defer group.Unlock()
+
+	var names []string
+
+	for name := range group.watchers {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+type staticResolver struct {
+	config scf4go.Config
+}
+
+// StaticResolver create resolver with static endpoints list from config,
+// e.g. { "endpoints": { "payments": ["127.0.0.1:8080", { "addr": "127.0.0.1:8081", "weight": 2 }] } }
+func StaticResolver(config scf4go.Config) (smf4go.Service, error) {
+	return &staticResolver{
+		config: config,
+	}, nil
+}
+
+func (static *staticResolver) Watch(name string, update func(endpoints []Endpoint)) (func(), error) {
+
+	var endpoints []Endpoint
+
+	if err := static.config.Get("endpoints", name).Scan(&endpoints); err != nil {
+		return nil, errors.Wrap(err, "service %s endpoints config error", name)
+	}
+
+	if len(endpoints) == 0 {
+		return nil, errors.Wrap(smf4go.ErrNotFound, "service %s endpoints not found", name)
+	}
+
+	update(endpoints)
+
+	return func() {}, nil
+}
+
+type fileResolver struct {
+	slf4go.Logger
+	watchers
+	path      string
+	interval  time.Duration
+	modTime   time.Time
+	endpoints map[string][]Endpoint
+	mutex     sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// FileResolver create resolver which load endpoints from json file and reload it when file changed,
+// e.g. { "path": "./endpoints.json", "interval": "5s" }. the file content is json object
+// map service name to endpoints: { "payments": ["127.0.0.1:8080", { "addr": "127.0.0.1:8081", "weight": 2 }] }
+func FileResolver(config scf4go.Config) (smf4go.Service, error) {
+	resolver := &fileResolver{
+		Logger:   slf4go.Get("grpcservice.resolver.file"),
+		path:     config.Get("path").String(""),
+		interval: config.Get("interval").Duration(time.Second * 5),
+		closed:   make(chan struct{}),
+	}
+
+	if resolver.path == "" {
+		return nil, errors.Wrap(smf4go.ErrConfig, "file resolver path not configured")
+	}
+
+	if _, err := resolver.load(); err != nil {
+		return nil, err
+	}
+
+	go resolver.watchLoop()
+
+	return resolver, nil
+}
+
+// load reload endpoints file if file changed, returns changed flag
+func (file *fileResolver) load() (bool, error) {
+	info, err := os.Stat(file.path)
+
+	if err != nil {
+		return false, errors.Wrap(err, "stat endpoints file %s error", file.path)
+	}
+
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if info.ModTime().Equal(file.modTime) {
+		return false, nil
+	}
+
+	data, err := ioutil.ReadFile(file.path)
+
+	if err != nil {
+		return false, errors.Wrap(err, "read endpoints file %s error", file.path)
+	}
+
+	var endpoints map[string][]Endpoint
+
+	if err := json.Unmarshal(data, &endpoints); err != nil {
+		return false, errors.Wrap(err, "unmarshal endpoints file %s error", file.path)
+	}
+
+	file.modTime = info.ModTime()
+	file.endpoints = endpoints
+
+	return true, nil
+}
+
+func (file *fileResolver) get(name string) []Endpoint {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	return file.endpoints[name]
+}
+
+func (file *fileResolver) watchLoop() {
+	ticker := time.NewTicker(file.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-file.closed:
+			return
+		case <-ticker.C:
+		}
+
+		old := make(map[string][]Endpoint)
+
+		names := file.names()
+
+		for _, name := range names {
+			old[name] = file.get(name)
+		}
+
+		changed, err := file.load()
+
+		if err != nil {
+			file.E("reload endpoints file error: {@err}", err)
+			continue
+		}
+
+		if !changed {
+			continue
+		}
+
+		for _, name := range names {
+			endpoints := file.get(name)
+
+			if !reflect.DeepEqual(old[name], endpoints) {
+				file.D("service {@name} endpoints changed {@endpoints}", name, endpoints)
+				file.notify(name, endpoints)
+			}
+		}
+	}
+}
+
+func (file *fileResolver) Watch(name string, update func(endpoints []Endpoint)) (func(), error) {
+	cancel := file.add(name, update)
+
+	update(file.get(name))
+
+	return cancel, nil
+}
+
+// Close implement smf4go.Closer, stop watching endpoints file
+func (file *fileResolver) Close() error {
+	file.closeOnce.Do(func() {
+		close(file.closed)
+	})
+
+	return nil
+}
+
+// resolverBuilder the grpc resolver.Builder for Scheme using register's Resolver
+type resolverBuilder struct {
+	register *registerImpl
+}
+
+func (builder *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	grpcResolver := &grpcResolver{
+		closed: make(chan struct{}),
+	}
+
+	builder.register.routines.Add(1)
+
+	go func() {
+		defer builder.register.routines.Done()
+		grpcResolver.watch(builder.register, target.Endpoint, cc)
+	}()
+
+	return grpcResolver, nil
+}
+
+func (builder *resolverBuilder) Scheme() string {
+	return Scheme
+}
+
+type grpcResolver struct {
+	mutex     sync.Mutex
+	cancel    func()
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// watch wait for register's Resolver service and watch endpoints of name
+func (r *grpcResolver) watch(register *registerImpl, name string, cc resolver.ClientConn) {
+
+	// the resolver is found in register End after mesh services bound
+	target, err := register.waitResolver(r.closed)
+
+	if err != nil {
+		register.D("wait resolver {@resolver} error: {@err}", register.resolver, err)
+		return
+	}
+
+	cancel, err := target.Watch(name, func(endpoints []Endpoint) {
+		var addresses []resolver.Address
+
+		for _, endpoint := range endpoints {
+			addresses = append(addresses, resolver.Address{
+				Addr:     endpoint.Addr,
+				Metadata: endpointWeight(endpoint.Weight),
+			})
+		}
+
+		register.D("service {@name} resolved endpoints {@endpoints}", name, endpoints)
+
+		cc.UpdateState(resolver.State{Addresses: addresses})
+	})
+
+	if err != nil {
+		register.E("watch service {@name} endpoints error: {@err}", name, err)
+		cc.ReportError(err)
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	select {
+	case <-r.closed:
+		cancel()
+	default:
+		r.cancel = cancel
+	}
+}
+
+func (r *grpcResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *grpcResolver) Close() {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
@@ -0,0 +1,165 @@
+package grpcservice
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/scf4go/reader/memory"
+	"github.com/libs4go/smf4go"
+	"github.com/libs4go/smf4go/service/localservice"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func newTestConfig(t *testing.T, data string) scf4go.Config {
+	config := scf4go.New()
+
+	if err := config.Load(memory.New(memory.Data(data, "json"))); err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func TestStaticResolver(t *testing.T) {
+	service, err := StaticResolver(newTestConfig(t, `{ "endpoints": { "payments": ["127.0.0.1:8080", { "addr": "127.0.0.1:8081", "weight": 2 }] } }`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var endpoints []Endpoint
+
+	if _, err := service.(Resolver).Watch("payments", func(update []Endpoint) { endpoints = update }); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(endpoints) != "[{127.0.0.1:8080 0} {127.0.0.1:8081 2}]" {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+
+	if _, err := service.(Resolver).Watch("unknown", func([]Endpoint) {}); !errors.Is(err, smf4go.ErrNotFound) {
+		t.Fatalf("expect ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	if _, err := FileResolver(newTestConfig(t, `{}`)); !errors.Is(err, smf4go.ErrConfig) {
+		t.Fatalf("expect ErrConfig, got %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "resolver")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "endpoints.json")
+
+	if err := ioutil.WriteFile(path, []byte(`{ "payments": ["127.0.0.1:8080"] }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := FileResolver(newTestConfig(t, fmt.Sprintf(`{ "path": %q, "interval": "10ms" }`, path)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer service.(smf4go.Closer).Close()
+
+	updates := make(chan []Endpoint, 2)
+
+	cancel, err := service.(Resolver).Watch("payments", func(endpoints []Endpoint) { updates <- endpoints })
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cancel()
+
+	if endpoints := <-updates; fmt.Sprint(endpoints) != "[{127.0.0.1:8080 0}]" {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{ "payments": ["127.0.0.1:8081"] }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// make sure the modification time changed on coarse file systems
+	modTime := time.Now().Add(time.Second)
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case endpoints := <-updates:
+		if fmt.Sprint(endpoints) != "[{127.0.0.1:8081 0}]" {
+			t.Fatalf("unexpected reloaded endpoints %v", endpoints)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("endpoints file changes not reloaded")
+	}
+}
+
+func TestEagerResolverConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := grpc.NewServer()
+
+	go upstream.Serve(listener)
+
+	defer upstream.Stop()
+
+	builder := smf4go.NewMeshBuilder()
+
+	local := localservice.New(builder)
+
+	local.Register("test.resolver", StaticResolver)
+
+	register := New("test.grpc", WithMeshBuilder(builder), WithLocalService(local), WithResolver("test.resolver"))
+
+	register.Remote("test.upstream", func(conn *grpc.ClientConn) (smf4go.Service, error) {
+		return conn, nil
+	})
+
+	config := newTestConfig(t, fmt.Sprintf(`{ "smf4go": { "service": {
+		"%s": { "network": "tcp", "laddr": "127.0.0.1:0" },
+		"test.resolver": { "endpoints": { "test.upstream": [%q] } },
+		"test.upstream": { "connect": "eager", "waitForReady": "5s" }
+	} } }`, DefaultProvider, listener.Addr().String()))
+
+	begin := time.Now()
+
+	if err := builder.Start(config); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(begin); elapsed > time.Second*2 {
+		t.Fatalf("eager connect wait %s for resolver", elapsed)
+	}
+
+	if state, err := register.State("test.upstream"); err != nil || state != connectivity.Ready {
+		t.Fatalf("expect ready connection, got %s %v", state, err)
+	}
+
+	// the connection returned as service is closed by mesh, not closed again by register
+	if err := builder.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
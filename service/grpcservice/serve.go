@@ -0,0 +1,176 @@
+package grpcservice
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc"
+)
+
+// serve loop state
+const (
+	serveIdle int32 = iota
+	serveRunning
+	serveFailed
+	serveStopped
+)
+
+// retryPolicy the serve loop retry policy, the retry delay grows exponentially from
+// backoff to retry.maxDelay, retry.maxRetries 0 means retry forever
+//
+//	{ "backoff": "1s", "retry": { "multiplier": 1.6, "jitter": 0.2, "maxDelay": "2m", "maxRetries": 0 } }
+type retryPolicy struct {
+	baseDelay  time.Duration
+	multiplier float64
+	jitter     float64
+	maxDelay   time.Duration
+	maxRetries int
+}
+
+func newRetryPolicy(config scf4go.Config) (*retryPolicy, error) {
+	policy := &retryPolicy{
+		baseDelay:  time.Second,
+		multiplier: config.Get("retry", "multiplier").Float64(1.6),
+		jitter:     config.Get("retry", "jitter").Float64(0.2),
+		maxDelay:   time.Minute * 2,
+	}
+
+	if delay, err := getDuration(config, "backoff"); err != nil {
+		return nil, err
+	} else if delay > 0 {
+		policy.baseDelay = delay
+	}
+
+	if delay, err := getDuration(config, "retry", "maxDelay"); err != nil {
+		return nil, err
+	} else if delay > 0 {
+		policy.maxDelay = delay
+	}
+
+	maxRetries, err := getSize(config, "retry", "maxRetries")
+
+	if err != nil {
+		return nil, err
+	}
+
+	policy.maxRetries = maxRetries
+
+	if policy.multiplier < 1 {
+		return nil, errors.Wrap(smf4go.ErrConfig, "invalid retry multiplier %v", policy.multiplier)
+	}
+
+	if policy.jitter < 0 || policy.jitter > 1 {
+		return nil, errors.Wrap(smf4go.ErrConfig, "invalid retry jitter %v", policy.jitter)
+	}
+
+	if policy.maxDelay < policy.baseDelay {
+		policy.maxDelay = policy.baseDelay
+	}
+
+	return policy, nil
+}
+
+// delay get the delay before retries+1 retry
+func (policy *retryPolicy) delay(retries int) time.Duration {
+	delay := float64(policy.baseDelay)
+
+	for i := 0; i < retries && delay < float64(policy.maxDelay); i++ {
+		delay *= policy.multiplier
+	}
+
+	if delay > float64(policy.maxDelay) {
+		delay = float64(policy.maxDelay)
+	}
+
+	delay *= 1 + policy.jitter*(rand.Float64()*2-1)
+
+	return time.Duration(delay)
+}
+
+// fatalServeError check if the grpc serve error can not be recovered by retry
+func fatalServeError(err error) bool {
+	if err == nil || err == grpc.ErrServerStopped || errors.Is(err, smf4go.ErrClosed) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// serveListener the grpc server listener of serve loop, grpc server close the listener when Serve returns,
+// the provider listener is kept open for retry unless the register closed
+type serveListener struct {
+	*registerImpl
+}
+
+func (listener *serveListener) Close() error {
+	select {
+	case <-listener.closed:
+		return listener.registerImpl.Close()
+	default:
+		return nil
+	}
+}
+
+// serve run grpc server until mesh shutdown or fatal error raised, the transient errors
+// are retried with policy, the retries count is reset if the server served longer than max delay
+func (extension *registerImpl) serve(policy *retryPolicy) {
+
+	retries := 0
+
+	for {
+		serveTime := time.Now()
+
+		err := extension.server.Serve(&serveListener{registerImpl: extension})
+
+		select {
+		case <-extension.closed:
+			return
+		default:
+		}
+
+		if fatalServeError(err) {
+			extension.E("grpc server {@name} stopped: {@err}", extension.name, err)
+			atomic.StoreInt32(&extension.state, serveFailed)
+			return
+		}
+
+		if time.Since(serveTime) > policy.maxDelay {
+			retries = 0
+		}
+
+		if policy.maxRetries > 0 && retries >= policy.maxRetries {
+			extension.E("grpc server {@name} stopped after {@retries} retries: {@err}", extension.name, retries, err)
+			atomic.StoreInt32(&extension.state, serveFailed)
+			return
+		}
+
+		delay := policy.delay(retries)
+		retries++
+
+		extension.W("grpc server {@name} serve error, retry in {@delay}: {@err}", extension.name, delay.String(), err)
+
+		select {
+		case <-extension.closed:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Health implement smf4go.HealthChecker, report grpc serve loop state
+func (extension *registerImpl) Health(ctx context.Context) smf4go.HealthStatus {
+	switch atomic.LoadInt32(&extension.state) {
+	case serveRunning:
+		return smf4go.HealthServing
+	case serveIdle:
+		return smf4go.HealthUnknown
+	default:
+		return smf4go.HealthNotServing
+	}
+}
@@ -0,0 +1,122 @@
+package grpcservice
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	_ "github.com/libs4go/scf4go/codec" //
+	"github.com/libs4go/scf4go/reader/memory"
+	"github.com/libs4go/smf4go"
+	"github.com/libs4go/smf4go/service/localservice"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	config := scf4go.New()
+
+	if err := config.Load(memory.New(memory.Data(`{ "backoff": "100ms", "retry": { "multiplier": 2, "jitter": 0, "maxDelay": "1s" } }`, "json"))); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := newRetryPolicy(config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var delays []time.Duration
+
+	for i := 0; i < 6; i++ {
+		delays = append(delays, policy.delay(i))
+	}
+
+	if fmt.Sprint(delays) != "[100ms 200ms 400ms 800ms 1s 1s]" {
+		t.Fatalf("unexpected delays %v", delays)
+	}
+
+	if !fatalServeError(grpc.ErrServerStopped) || !fatalServeError(errors.Wrap(smf4go.ErrClosed, "closed")) {
+		t.Fatal("expect fatal serve error")
+	}
+
+	if fatalServeError(errors.New("accept: too many open files")) {
+		t.Fatal("expect transient serve error")
+	}
+}
+
+// flakyListener tcp listener whose first Accept fails with transient error
+type flakyListener struct {
+	net.Listener
+	failed int32
+}
+
+func (listener *flakyListener) Accept() (net.Conn, error) {
+	if atomic.CompareAndSwapInt32(&listener.failed, 0, 1) {
+		return nil, errors.New("accept: too many open files")
+	}
+
+	return listener.Listener.Accept()
+}
+
+type flakyProvider struct {
+	listener *flakyListener
+}
+
+func (provider *flakyProvider) Listener() net.Listener {
+	return provider.listener
+}
+
+func (provider *flakyProvider) Connect(ctx context.Context, remote string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", remote)
+}
+
+func (provider *flakyProvider) Close() error {
+	return provider.listener.Close()
+}
+
+func TestServeRetry(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := smf4go.NewMeshBuilder()
+
+	local := localservice.New(builder)
+
+	local.Register("test.provider", func(config scf4go.Config) (smf4go.Service, error) {
+		return &flakyProvider{listener: &flakyListener{Listener: listener}}, nil
+	})
+
+	New("test.grpc", WithMeshBuilder(builder), WithLocalService(local), WithProvider("test.provider"))
+
+	if err := builder.Start(newTestConfig(t, `{ "smf4go": { "service": { "test.grpc": { "backoff": "10ms" } } } }`)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithInsecure())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// the provider listener is still open after the failed serve, the server resumes serving
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true)); err != nil {
+		t.Fatalf("expect serving resumed, got %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package grpcservice
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/slf4go"
+	"github.com/libs4go/smf4go"
+	"google.golang.org/grpc/credentials"
+)
+
+// tlsReloader build tls.Config from files and rebuild it when files changed
+type tlsReloader struct {
+	slf4go.Logger
+	files    []string                    // watched files
+	interval time.Duration               // files change check interval
+	build    func() (*tls.Config, error) // tls.Config builder
+	mutex    sync.Mutex                  // mutex
+	config   *tls.Config                 // current tls config
+	modTimes []time.Time                 // watched files modify time
+	checked  time.Time                   // last check time
+}
+
+func newTLSReloader(interval time.Duration, build func() (*tls.Config, error), files ...string) (*tlsReloader, error) {
+	reloader := &tlsReloader{
+		Logger:   slf4go.Get("grpcservice.tls"),
+		files:    files,
+		interval: interval,
+		build:    build,
+	}
+
+	modTimes, err := reloader.stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := build()
+
+	if err != nil {
+		return nil, err
+	}
+
+	reloader.config = config
+	reloader.modTimes = modTimes
+	reloader.checked = time.Now()
+
+	return reloader, nil
+}
+
+func (reloader *tlsReloader) stat() ([]time.Time, error) {
+	var modTimes []time.Time
+
+	for _, file := range reloader.files {
+		info, err := os.Stat(file)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "stat tls file %s error", file)
+		}
+
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	return modTimes, nil
+}
+
+// get get current tls.Config, if files changed rebuild it. if rebuild failed
+// the previous tls.Config will be returned
+func (reloader *tlsReloader) get() *tls.Config {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+
+	if time.Since(reloader.checked) < reloader.interval {
+		return reloader.config
+	}
+
+	reloader.checked = time.Now()
+
+	modTimes, err := reloader.stat()
+
+	if err != nil {
+		reloader.E("check tls files error: {@err}", err)
+		return reloader.config
+	}
+
+	changed := false
+
+	for i := range modTimes {
+		if !modTimes[i].Equal(reloader.modTimes[i]) {
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return reloader.config
+	}
+
+	config, err := reloader.build()
+
+	if err != nil {
+		reloader.E("reload tls files {@files} error: {@err}", reloader.files, err)
+		return reloader.config
+	}
+
+	reloader.I("reload tls files {@files} -- success", reloader.files)
+
+	reloader.config = config
+	reloader.modTimes = modTimes
+
+	return config
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "read ca file %s error", file)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Wrap(smf4go.ErrNotFound, "certificates in ca file %s not found", file)
+	}
+
+	return pool, nil
+}
+
+// newServerTLS create server side tls reloader from config, returns nil if tls not configured.
+// e.g. { "tls": { "cert": "server.crt", "key": "server.key", "clientCA": "ca.crt", "reload": "10s" } },
+// if clientCA configured, the client certificate is required and verified
+func newServerTLS(config scf4go.Config) (*tlsReloader, error) {
+	cert := config.Get("tls", "cert").String("")
+	key := config.Get("tls", "key").String("")
+	clientCA := config.Get("tls", "clientCA").String("")
+
+	if cert == "" && key == "" {
+		return nil, nil
+	}
+
+	files := []string{cert, key}
+
+	if clientCA != "" {
+		files = append(files, clientCA)
+	}
+
+	return newTLSReloader(config.Get("tls", "reload").Duration(time.Second*10), func() (*tls.Config, error) {
+		certificate, err := tls.LoadX509KeyPair(cert, key)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "load server certificate %s error", cert)
+		}
+
+		tlsConfig := &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			NextProtos:   []string{"h2"},
+			MinVersion:   tls.VersionTLS12,
+		}
+
+		if clientCA != "" {
+			pool, err := loadCertPool(clientCA)
+
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConfig.ClientCAs = pool
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		return tlsConfig, nil
+	}, files...)
+}
+
+// newClientTLS create client side tls reloader from config, returns nil if tls not configured.
+// e.g. { "tls": { "ca": "ca.crt", "cert": "client.crt", "key": "client.key", "serverName": "payments", "reload": "10s" } }
+func newClientTLS(config scf4go.Config) (*tlsReloader, error) {
+	if len(config.Get("tls").StringMap(nil)) == 0 {
+		return nil, nil
+	}
+
+	ca := config.Get("tls", "ca").String("")
+	cert := config.Get("tls", "cert").String("")
+	key := config.Get("tls", "key").String("")
+	serverName := config.Get("tls", "serverName").String("")
+
+	var files []string
+
+	if ca != "" {
+		files = append(files, ca)
+	}
+
+	if cert != "" || key != "" {
+		files = append(files, cert, key)
+	}
+
+	return newTLSReloader(config.Get("tls", "reload").Duration(time.Second*10), func() (*tls.Config, error) {
+		tlsConfig := &tls.Config{
+			ServerName: serverName,
+			MinVersion: tls.VersionTLS12,
+		}
+
+		if ca != "" {
+			pool, err := loadCertPool(ca)
+
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConfig.RootCAs = pool
+		}
+
+		if cert != "" || key != "" {
+			certificate, err := tls.LoadX509KeyPair(cert, key)
+
+			if err != nil {
+				return nil, errors.Wrap(err, "load client certificate %s error", cert)
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+
+		return tlsConfig, nil
+	}, files...)
+}
+
+// SecureProvider Provider whose listener connections must be secured, the grpc server handshakes
+// connections accepted from provider listener with Credentials, nil Credentials means insecure
+type SecureProvider interface {
+	Provider
+	Credentials() credentials.TransportCredentials
+}
+
+// serverCredentials register's grpc server transport credentials, the connections from provider listener
+// are handshaked by provider Credentials, so peer.AuthInfo carries the client identity. the in-process
+// endpoint connections are insecure
+type serverCredentials struct {
+	register *registerImpl
+}
+
+func (creds *serverCredentials) provider() credentials.TransportCredentials {
+	if provider, ok := creds.register.getProvider().(SecureProvider); ok {
+		return provider.Credentials()
+	}
+
+	return nil
+}
+
+func (creds *serverCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.Wrap(smf4go.ErrInternal, "grpc server credentials can't be used by client")
+}
+
+func (creds *serverCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if _, ok := conn.(*insecureConn); ok {
+		return conn, nil, nil
+	}
+
+	if provider := creds.provider(); provider != nil {
+		return provider.ServerHandshake(conn)
+	}
+
+	return conn, nil, nil
+}
+
+func (creds *serverCredentials) Info() credentials.ProtocolInfo {
+	if provider := creds.provider(); provider != nil {
+		return provider.Info()
+	}
+
+	return credentials.ProtocolInfo{}
+}
+
+func (creds *serverCredentials) Clone() credentials.TransportCredentials {
+	return &serverCredentials{register: creds.register}
+}
+
+func (creds *serverCredentials) OverrideServerName(serverName string) error {
+	return nil
+}
+
+// reloadingCredentials grpc transport credentials using tls.Config from reloader
+type reloadingCredentials struct {
+	reloader   *tlsReloader
+	serverName string
+}
+
+func newReloadingCredentials(reloader *tlsReloader) credentials.TransportCredentials {
+	return &reloadingCredentials{
+		reloader: reloader,
+	}
+}
+
+func (creds *reloadingCredentials) current() credentials.TransportCredentials {
+	tlsConfig := creds.reloader.get().Clone()
+
+	if creds.serverName != "" {
+		tlsConfig.ServerName = creds.serverName
+	}
+
+	return credentials.NewTLS(tlsConfig)
+}
+
+func (creds *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return creds.current().ClientHandshake(ctx, authority, conn)
+}
+
+func (creds *reloadingCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return creds.current().ServerHandshake(conn)
+}
+
+func (creds *reloadingCredentials) Info() credentials.ProtocolInfo {
+	return creds.current().Info()
+}
+
+func (creds *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingCredentials{
+		reloader:   creds.reloader,
+		serverName: creds.serverName,
+	}
+}
+
+func (creds *reloadingCredentials) OverrideServerName(serverName string) error {
+	creds.serverName = serverName
+	return nil
+}
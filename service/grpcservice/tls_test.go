@@ -0,0 +1,309 @@
+package grpcservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/smf4go"
+	"github.com/libs4go/smf4go/service/localservice"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// testCA test certificate authority which issues certificates into dir
+type testCA struct {
+	dir         string
+	key         *ecdsa.PrivateKey
+	certificate *x509.Certificate
+	serial      int64
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	ca := &testCA{dir: dir}
+
+	ca.key, ca.certificate = ca.issue(t, "ca", nil)
+
+	return ca
+}
+
+// issue issue certificate with common name, signed by ca if parent is not nil and write pem files <name>.crt and <name>.key
+func (ca *testCA) issue(t *testing.T, name string, parent *testCA) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca.serial++
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca.write(t, name+".crt", &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	ca.write(t, name+".key", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	certificate, err := x509.ParseCertificate(der)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, certificate
+}
+
+func (ca *testCA) write(t *testing.T, name string, block *pem.Block) {
+	path := ca.path(name)
+
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// make sure the modification time changed on coarse file systems
+	modTime := time.Now().Add(time.Duration(ca.serial) * time.Second)
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+func newTempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "grpcservice")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// whoamiService Local grpc service smf4go.test.WhoAmI, reply the client certificate common name
+type whoamiService struct{}
+
+func (service *whoamiService) GrpcHandler(server *grpc.Server) error {
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "smf4go.test.WhoAmI",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "WhoAmI",
+				Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+					if err := dec(&healthpb.HealthCheckRequest{}); err != nil {
+						return nil, err
+					}
+
+					name := "insecure"
+
+					if p, ok := peer.FromContext(ctx); ok {
+						if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+							name = info.State.PeerCertificates[0].Subject.CommonName
+						}
+					}
+
+					return &healthpb.HealthCheckRequest{Service: name}, nil
+				},
+			},
+		},
+	}, service)
+
+	return nil
+}
+
+func whoami(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+	response := &healthpb.HealthCheckRequest{}
+
+	if err := conn.Invoke(ctx, "/smf4go.test.WhoAmI/WhoAmI", &healthpb.HealthCheckRequest{}, response); err != nil {
+		return "", err
+	}
+
+	return response.Service, nil
+}
+
+func TestServerTLS(t *testing.T) {
+	dir, cleanup := newTempDir(t)
+	defer cleanup()
+
+	ca := newTestCA(t, dir)
+	ca.issue(t, "server", ca)
+	ca.issue(t, "client", ca)
+
+	builder := smf4go.NewMeshBuilder()
+
+	register := New("test.grpc", WithMeshBuilder(builder), WithLocalService(localservice.New(builder)))
+
+	register.Local("test.whoami", func(config scf4go.Config) (Service, error) {
+		return &whoamiService{}, nil
+	})
+
+	config := newTestConfig(t, fmt.Sprintf(`{ "smf4go": { "service": { "%s": {
+		"network": "tcp", "laddr": "127.0.0.1:0",
+		"tls": { "cert": %q, "key": %q, "clientCA": %q }
+	} } } }`, DefaultProvider, ca.path("server.crt"), ca.path("server.key"), ca.path("ca.crt")))
+
+	if err := builder.Start(config); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	addr, err := register.ServerAddr(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+
+	clientCertificate, err := tls.LoadX509KeyPair(ca.path("client.crt"), ca.path("client.key"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := grpc.DialContext(ctx, addr.String(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{clientCertificate},
+	})))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// the client identity of mtls connection reaches the service
+	if name, err := whoami(ctx, conn); err != nil || name != "client" {
+		t.Fatalf("expect client identity, got %s %v", name, err)
+	}
+
+	anonymous, err := grpc.DialContext(ctx, addr.String(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		RootCAs: pool,
+	})))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer anonymous.Close()
+
+	if _, err := whoami(ctx, anonymous); err == nil {
+		t.Fatal("expect client without certificate rejected")
+	}
+
+	// the in-process endpoint is insecure
+	if name, err := whoami(ctx, register.(*registerImpl).inproc.conn); err != nil || name != "insecure" {
+		t.Fatalf("expect insecure in-process call, got %s %v", name, err)
+	}
+}
+
+func TestTLSReloader(t *testing.T) {
+	dir, cleanup := newTempDir(t)
+	defer cleanup()
+
+	ca := newTestCA(t, dir)
+	ca.issue(t, "server", ca)
+
+	config := newTestConfig(t, fmt.Sprintf(`{ "tls": { "cert": %q, "key": %q, "reload": "1ns" } }`,
+		ca.path("server.crt"), ca.path("server.key")))
+
+	reloader, err := newServerTLS(config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := reloader.get().Certificates[0].Certificate[0]
+
+	ca.issue(t, "server", ca)
+
+	if reloaded := reloader.get().Certificates[0].Certificate[0]; bytes.Equal(current, reloaded) {
+		t.Fatal("expect certificate reloaded")
+	}
+
+	current = reloader.get().Certificates[0].Certificate[0]
+
+	// the broken files keep the previous config
+	ca.write(t, "server.crt", &pem.Block{Type: "CERTIFICATE", Bytes: []byte("broken")})
+
+	if reloaded := reloader.get().Certificates[0].Certificate[0]; !bytes.Equal(current, reloaded) {
+		t.Fatal("expect previous certificate kept")
+	}
+}
+
+func TestTLSConfigError(t *testing.T) {
+	dir, cleanup := newTempDir(t)
+	defer cleanup()
+
+	ca := newTestCA(t, dir)
+	ca.issue(t, "server", ca)
+
+	if _, err := newServerTLS(newTestConfig(t, `{ "tls": { "cert": "missing.crt", "key": "missing.key" } }`)); err == nil {
+		t.Fatal("expect missing certificate error")
+	}
+
+	if _, err := newServerTLS(newTestConfig(t, fmt.Sprintf(`{ "tls": { "cert": %q, "key": %q, "clientCA": %q } }`,
+		ca.path("server.crt"), ca.path("server.key"), ca.path("server.key")))); err == nil {
+		t.Fatal("expect invalid client ca error")
+	}
+
+	if _, err := newClientTLS(newTestConfig(t, fmt.Sprintf(`{ "tls": { "ca": %q } }`, ca.path("server.key")))); err == nil {
+		t.Fatal("expect invalid ca error")
+	}
+
+	if reloader, err := newServerTLS(newTestConfig(t, `{}`)); err != nil || reloader != nil {
+		t.Fatalf("expect tls not configured, got %v %v", reloader, err)
+	}
+}
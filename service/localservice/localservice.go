@@ -1,6 +1,7 @@
 package localservice
 
 import (
+	"reflect"
 	"sync"
 
 	"github.com/libs4go/errors"
@@ -8,40 +9,93 @@ import (
 	"github.com/libs4go/smf4go"
 )
 
+// ExtensionName the local service extension name
+const ExtensionName = "smf4go.extension.local"
+
 // F .
 type F func(config scf4go.Config) (smf4go.Service, error)
 
 // LocalService .
 type LocalService interface {
-	Register(name string, f F)
+	Register(name string, f F, options ...Option)
 }
 
 type registerEntry struct {
-	Name string
-	F    F
+	Name      string
+	F         F
+	Scope     smf4go.Scope
+	Prototype reflect.Type // service type declared for validating
+	Required  []string     // required config keys declared for validating
+}
+
+// Option local service register option
+type Option func(*registerEntry)
+
+// WithScope register service with instance scope, the default scope is smf4go.ScopeSingleton.
+// the transient and context scoped services are created by f on each lookup, they are not started or stopped by mesh
+func WithScope(scope smf4go.Scope) Option {
+	return func(entry *registerEntry) {
+		entry.Scope = scope
+	}
+}
+
+// WithPrototype declare the service type for MeshBuilder.Validate, e.g. WithPrototype((*myService)(nil)),
+// the inject tags of service are checked without creating it
+func WithPrototype(service smf4go.Service) Option {
+	return func(entry *registerEntry) {
+		entry.Prototype = reflect.TypeOf(service)
+	}
+}
+
+// WithRequired declare the required config keys for MeshBuilder.Validate, nested keys are separated by dot
+func WithRequired(keys ...string) Option {
+	return func(entry *registerEntry) {
+		entry.Required = append(entry.Required, keys...)
+	}
+}
+
+// scopedFactory create service instances with scope other than singleton
+type scopedFactory struct {
+	scope  smf4go.Scope
+	f      F
+	config scf4go.Config
+}
+
+func (factory *scopedFactory) Scope() smf4go.Scope {
+	return factory.scope
+}
+
+func (factory *scopedFactory) New() (smf4go.Service, error) {
+	return factory.f(factory.config)
 }
 
 type localServiceExtension struct {
-	creators map[string]F
-	orders   []registerEntry
+	creators map[string]*registerEntry
+	orders   []*registerEntry
 }
 
 func newExtension() *localServiceExtension {
 	return &localServiceExtension{
-		creators: make(map[string]F),
+		creators: make(map[string]*registerEntry),
 	}
 }
 
-func (extension *localServiceExtension) Register(name string, f F) {
-	extension.creators[name] = f
-	extension.orders = append(extension.orders, registerEntry{
+func (extension *localServiceExtension) Register(name string, f F, options ...Option) {
+	entry := &registerEntry{
 		Name: name,
 		F:    f,
-	})
+	}
+
+	for _, option := range options {
+		option(entry)
+	}
+
+	extension.creators[name] = entry
+	extension.orders = append(extension.orders, entry)
 }
 
 func (extension *localServiceExtension) Name() string {
-	return "smf4go.extension.local"
+	return ExtensionName
 }
 
 func (extension *localServiceExtension) Begin(config scf4go.Config, builder smf4go.MeshBuilder) error {
@@ -53,42 +107,67 @@ func (extension *localServiceExtension) Begin(config scf4go.Config, builder smf4
 	return nil
 }
 
+// Declare implement smf4go.Declarer
+func (extension *localServiceExtension) Declare() []smf4go.ServiceDeclaration {
+	var declarations []smf4go.ServiceDeclaration
+
+	for _, entry := range extension.orders {
+		declarations = append(declarations, smf4go.ServiceDeclaration{
+			Name:     entry.Name,
+			Type:     entry.Prototype,
+			Required: entry.Required,
+		})
+	}
+
+	return declarations
+}
+
 func (extension *localServiceExtension) CreateSerivce(serviceName string, config scf4go.Config) (smf4go.Service, error) {
-	f, ok := extension.creators[serviceName]
+	entry, ok := extension.creators[serviceName]
 
 	if !ok {
 		return nil, errors.Wrap(smf4go.ErrNotFound, "service %s not found", serviceName)
 	}
 
-	return f(config)
+	if entry.Scope != smf4go.ScopeSingleton {
+		return &scopedFactory{scope: entry.Scope, f: entry.F, config: config}, nil
+	}
+
+	return entry.F(config)
 }
 
 func (extension *localServiceExtension) End() error {
 	return nil
 }
 
-var extension *localServiceExtension
-var once sync.Once
+// extensions the LocalService extensions registered on mesh builders
+var extensions = make(map[smf4go.MeshBuilder]*localServiceExtension)
+var extensionsMutex sync.Mutex
 
 // Get get sigleton LocalService
 func Get() LocalService {
-	once.Do(func() {
-		extension = newExtension()
-		smf4go.Builder().RegisterExtension(extension)
-	})
-
-	return extension
+	return New(smf4go.Builder())
 }
 
 // Register .
-func Register(name string, f F) {
-	Get().Register(name, f)
+func Register(name string, f F, options ...Option) {
+	Get().Register(name, f, options...)
 }
 
-// New create LocalService with provider smf4go.MeshBuilder
+// New get LocalService of provider smf4go.MeshBuilder, the extension is created and registered
+// on the first call, the following calls with the same builder return it
 func New(builder smf4go.MeshBuilder) LocalService {
+	extensionsMutex.Lock()
+	defer extensionsMutex.Unlock()
+
+	if extension, ok := extensions[builder]; ok {
+		return extension
+	}
+
 	extension := newExtension()
 	builder.RegisterExtension(extension)
 
+	extensions[builder] = extension
+
 	return extension
 }
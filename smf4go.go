@@ -1,8 +1,13 @@
 package smf4go
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/libs4go/errors"
 	"github.com/libs4go/scf4go"
@@ -15,12 +20,43 @@ const errVendor = "smf4go"
 
 // errors
 var (
-	ErrInternal = errors.New("the internal error", errors.WithVendor(errVendor))
-	ErrAgent    = errors.New("agent implement not found", errors.WithVendor(errVendor))
-	ErrExists   = errors.New("target resource exists", errors.WithVendor(errVendor))
-	ErrNotFound = errors.New("target resource not found", errors.WithVendor(errVendor))
+	ErrInternal   = errors.New("the internal error", errors.WithVendor(errVendor))
+	ErrAgent      = errors.New("agent implement not found", errors.WithVendor(errVendor))
+	ErrExists     = errors.New("target resource exists", errors.WithVendor(errVendor))
+	ErrNotFound   = errors.New("target resource not found", errors.WithVendor(errVendor))
+	ErrTimeout    = errors.New("operation timeout", errors.WithVendor(errVendor))
+	ErrCycle      = errors.New("dependency cycle detected", errors.WithVendor(errVendor))
+	ErrConfig     = errors.New("invalid config", errors.WithVendor(errVendor))
+	ErrClosed     = errors.New("resource closed", errors.WithVendor(errVendor))
+	ErrNotStarted = errors.New("mesh not started", errors.WithVendor(errVendor))
+	ErrType       = errors.New("type mismatch", errors.WithVendor(errVendor))
+	ErrAmbiguous  = errors.New("ambiguous services", errors.WithVendor(errVendor))
+	ErrScope      = errors.New("service scope mismatch", errors.WithVendor(errVendor))
 )
 
+// MultiError aggregate errors raised by batch operations, e.g. MeshBuilder.Stop
+type MultiError []error
+
+func (errs MultiError) Error() string {
+	var buff bytes.Buffer
+
+	buff.WriteString(fmt.Sprintf("%d errors occurred:", len(errs)))
+
+	for _, err := range errs {
+		buff.WriteString(fmt.Sprintf("\n\t* %s", err))
+	}
+
+	return buff.String()
+}
+
+func (errs MultiError) errorOrNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
 // Service smf4go service base interface has nothing
 type Service interface{}
 
@@ -30,6 +66,27 @@ type Runnable interface {
 	Start() error
 }
 
+// Stoppable service which support graceful stop, MeshBuilder.Stop will call Stop
+// in reverse start order
+type Stoppable interface {
+	Service
+	Stop(ctx context.Context) error
+}
+
+// Closer service which hold resources must be released when mesh stopped,
+// Close will not be called if the service implement Stoppable
+type Closer interface {
+	Service
+	Close() error
+}
+
+// StartedObserver service which is notified after all services started and mesh is serving,
+// MeshStarted is called in creation order and must not block
+type StartedObserver interface {
+	Service
+	MeshStarted()
+}
+
 // ServiceRegisterEntry .
 type ServiceRegisterEntry struct {
 	Name    string  // service name
@@ -41,7 +98,22 @@ type MeshBuilder interface {
 	RegisterService(extensionName string, serviceName string) error
 	RegisterExtension(extension Extension) error
 	Start(config scf4go.Config) error
-	FindService(name string, service interface{})
+	// Reload apply new config to the Reconfigurable services whose config changed
+	Reload(config scf4go.Config) error
+	// Validate check the mesh can be started with config without starting it, returns all problems found
+	Validate(config scf4go.Config) error
+	Stop(ctx context.Context) error
+	Health(ctx context.Context) *HealthReport
+	FindService(name string, service interface{}) error
+	// FindServiceContext find service by name like FindService, the context scoped services
+	// are shared by the service scope context, see WithServiceScope
+	FindServiceContext(ctx context.Context, name string, service interface{}) error
+	// FindServiceByType find the single service which can be assigned to service pointer,
+	// returns ErrAmbiguous if more than one service matched
+	FindServiceByType(service interface{}) error
+	// FindServices find all services which can be assigned to the element of services slice pointer,
+	// the services are in creation order
+	FindServices(services interface{}) error
 }
 
 // Extension smf4go service handle extension
@@ -52,48 +124,111 @@ type Extension interface {
 	End() error
 }
 
+// Prioritized extension with begin priority, extensions with lower priority begin first.
+// extensions not implement Prioritized have priority 0
+type Prioritized interface {
+	Priority() int
+}
+
+// Dependent extension which must begin after the extensions it depends on
+type Dependent interface {
+	DependsOn() []string
+}
+
+// Teardown extension which support teardown routine, called after all services stopped
+type Teardown interface {
+	Teardown(ctx context.Context) error
+}
+
 type meshBuilderImpl struct {
-	slf4go.Logger                        // mixin logger
-	injector        sdi4go.Injector      // injector context
-	registers       map[string]string    // registers services
-	orderServices   []string             //order service name
-	extensions      map[string]Extension // extensions
-	orderExtensions []Extension          // order extension names
-	started         atomic.Value         // started
+	slf4go.Logger                          // mixin logger
+	injector        atomic.Value           // sdi4go.Injector of current run, recreated when mesh stopped
+	registers       map[string][]string    // registers services and the candidate extensions
+	orderServices   []string               //order service name
+	extensions      map[string]Extension   // extensions
+	orderExtensions []Extension            // order extension names
+	started         atomic.Value           // started
+	bound           atomic.Value           // services snapshot bound into injector, nil if not bound
+	mutex           sync.Mutex             // lifecycle mutex
+	services        []ServiceRegisterEntry // created services
+	runnables       []ServiceRegisterEntry // started runnable services
+	pending         []*pendingStart        // runnable services which start routine timeout
+	begun           []Extension            // extensions which Begin routine called
+	serving         atomic.Value           // services snapshot of started mesh
+	config          scf4go.Config          // config of started mesh
 }
 
 // NewMeshBuilder create new mesh builder
 func NewMeshBuilder() MeshBuilder {
 	impl := &meshBuilderImpl{
 		Logger:     slf4go.Get("smf4go"),
-		registers:  make(map[string]string),
+		registers:  make(map[string][]string),
 		extensions: make(map[string]Extension),
-		injector:   sdi4go.New(),
 	}
 
+	impl.injector.Store(sdi4go.New())
 	impl.started.Store(false)
+	impl.bound.Store([]ServiceRegisterEntry(nil))
 
 	return impl
 }
 
+// RegisterService register service created by extension, the service can be registered by more than one
+// extensions, which one creates the service is selected by config smf4go.service.<name>.extension
 func (builder *meshBuilderImpl) RegisterService(extensionName string, serviceName string) error {
 
-	_, ok := builder.registers[serviceName]
-
-	if ok {
-		return errors.Wrap(ErrExists, "service %s exists", serviceName)
+	for _, name := range builder.registers[serviceName] {
+		if name == extensionName {
+			return errors.Wrap(ErrExists, "service %s exists", serviceName)
+		}
 	}
 
 	if _, ok := builder.extensions[extensionName]; !ok {
 		return errors.Wrap(ErrNotFound, "extension %s not found", extensionName)
 	}
 
-	builder.registers[serviceName] = extensionName
-	builder.orderServices = append(builder.orderServices, serviceName)
+	if len(builder.registers[serviceName]) == 0 {
+		builder.orderServices = append(builder.orderServices, serviceName)
+	}
+
+	builder.registers[serviceName] = append(builder.registers[serviceName], extensionName)
 
 	return nil
 }
 
+// selectExtension select the extension which creates service
+func (builder *meshBuilderImpl) selectExtension(config scf4go.Config, serviceName string) (Extension, error) {
+	name, err := selectCandidate(config, serviceName, builder.registers[serviceName])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return builder.extensions[name], nil
+}
+
+// selectCandidate select extension name from service candidate extensions by service config
+func selectCandidate(config scf4go.Config, serviceName string, candidates []string) (string, error) {
+	selected := config.Get("extension").String("")
+
+	if selected == "" {
+		if len(candidates) > 1 {
+			return "", errors.Wrap(ErrAmbiguous, "service %s registered by extensions [%s], select one by extension config",
+				serviceName, strings.Join(candidates, ", "))
+		}
+
+		return candidates[0], nil
+	}
+
+	for _, name := range candidates {
+		if name == selected {
+			return name, nil
+		}
+	}
+
+	return "", errors.Wrap(ErrNotFound, "service %s not registered by extension %s", serviceName, selected)
+}
+
 func (builder *meshBuilderImpl) RegisterExtension(extension Extension) error {
 
 	_, ok := builder.extensions[extension.Name()]
@@ -108,91 +243,413 @@ func (builder *meshBuilderImpl) RegisterExtension(extension Extension) error {
 	return nil
 }
 
-func (builder *meshBuilderImpl) FindService(name string, service interface{}) {
+// FindService find service by name and assign it to service pointer, the services can be found after they
+// are bound and injected, so extensions End routine and Runnable services Start can find services.
+// returns ErrNotStarted if services not bound, ErrNotFound if the service not exists, and ErrType if
+// the service can't be assigned to service pointer
+func (builder *meshBuilderImpl) FindService(name string, service interface{}) error {
 
-	if !builder.started.Load().(bool) {
-		builder.D("mesh builder processing, FindService not valid")
-		return
+	services, ok := builder.boundServices()
+
+	if !ok {
+		return errors.Wrap(ErrNotStarted, "find service %s error", name)
 	}
 
-	builder.injector.Create(name, service)
+	return builder.find(services, nil, name, service)
+}
+
+// MustFindService find service by name like MeshBuilder.FindService, panic if error occurred
+func MustFindService(builder MeshBuilder, name string, service interface{}) {
+	if err := builder.FindService(name, service); err != nil {
+		panic(err)
+	}
 }
 
 func (builder *meshBuilderImpl) Start(config scf4go.Config) error {
 
-	for _, extension := range builder.extensions {
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+
+	// the running mesh is not rolled back, it must be stopped before started again
+	if builder.started.Load().(bool) {
+		return errors.Wrap(ErrExists, "mesh already started")
+	}
+
+	report := newStartupReport()
+
+	defer report.log(builder, time.Now())
+
+	extensions, err := builder.extensionOrder()
+
+	if err != nil {
+		return builder.abort(errors.Wrap(err, "sort extensions error"))
+	}
+
+	for _, extension := range extensions {
 		subconfig := config.SubConfig("smf4go", "extension", extension.Name())
 
 		builder.D("call extension {@ext} initialize routine", extension.Name())
 
 		if err := extension.Begin(subconfig, builder); err != nil {
-			return errors.Wrap(err, "start extension %s error", extension.Name())
+			return builder.abort(errors.Wrap(err, "start extension %s error", extension.Name()))
 		}
 
+		builder.begun = append(builder.begun, extension)
+
 		builder.D("call extension {@ext} initialize routine -- success", extension.Name())
 	}
 
-	var services []ServiceRegisterEntry
-
 	for _, serviceName := range builder.orderServices {
 		subconfig := config.SubConfig("smf4go", "service", serviceName)
 
-		extension := builder.extensions[builder.registers[serviceName]]
+		if !subconfig.Get("enabled").Bool(true) {
+			builder.D("service {@service} disabled", serviceName)
+			continue
+		}
+
+		extension, err := builder.selectExtension(subconfig, serviceName)
+
+		if err != nil {
+			return builder.abort(err)
+		}
 
 		builder.D("create service {@service} by extension {@ext}", serviceName, extension.Name())
 
-		service, err := extension.CreateSerivce(serviceName, subconfig)
+		var service Service
+
+		err = report.record(serviceName, createPhase, func() (err error) {
+			service, err = extension.CreateSerivce(serviceName, subconfig)
+			return
+		})
 
 		if err != nil {
-			return errors.Wrap(err, "create service %s by extension %s error", serviceName, extension.Name())
+			return builder.abort(errors.Wrap(err, "create service %s by extension %s error", serviceName, extension.Name()))
 		}
 
 		builder.D("create service {@service} by extension {@ext} -- success", serviceName, extension.Name())
 
-		services = append(services, ServiceRegisterEntry{Name: serviceName, Service: service})
+		builder.services = append(builder.services, ServiceRegisterEntry{Name: serviceName, Service: service})
 	}
 
-	for _, entry := range services {
-		builder.injector.Bind(entry.Name, sdi4go.Singleton(entry.Service))
+	injector := builder.injector.Load().(sdi4go.Injector)
+
+	for _, entry := range builder.services {
+		if err := injector.Bind(entry.Name, sdi4go.Singleton(entry.Service)); err != nil {
+			return builder.abort(errors.Wrap(ErrInternal, "bind service %s error: %s", entry.Name, err))
+		}
 	}
 
-	for _, entry := range services {
+	for _, entry := range builder.services {
 
 		builder.D("bind service {@service}", entry.Name)
 
-		if err := builder.injector.Inject(entry.Service); err != nil {
-			return errors.Wrap(err, "service %s bind error", entry.Name)
+		err := report.record(entry.Name, injectPhase, func() error {
+			return builder.inject(entry, builder.services, nil)
+		})
+
+		if err != nil {
+			return builder.abort(errors.Wrap(err, "service %s bind error", entry.Name))
 		}
 
 		builder.D("bind service {@service} -- success", entry.Name)
 	}
 
-	for _, extension := range builder.extensions {
+	builder.bound.Store(append([]ServiceRegisterEntry(nil), builder.services...))
+
+	runnables, err := builder.runnableOrder()
+
+	if err != nil {
+		return builder.abort(errors.Wrap(err, "sort runnable services error"))
+	}
+
+	for _, extension := range extensions {
 
 		builder.D("call extension {@ext} finally routine", extension.Name())
 
 		if err := extension.End(); err != nil {
-			return errors.Wrap(err, "extension %s finally routine error", extension.Name())
+			return builder.abort(errors.Wrap(err, "extension %s finally routine error", extension.Name()))
 		}
 
 		builder.D("call extension {@ext} finally routine -- success", extension.Name())
 	}
 
-	for _, entry := range services {
-		if runnable, ok := entry.Service.(Runnable); ok {
-			builder.D("start runnable service {@service}", entry.Name)
-			if err := runnable.Start(); err != nil {
-				return errors.Wrap(err, "start service %s error", entry.Name)
+	if err := builder.startServices(config, runnables, report); err != nil {
+		return builder.abort(err)
+	}
+
+	builder.config = config
+	builder.serving.Store(append([]ServiceRegisterEntry(nil), builder.services...))
+	builder.started.Store(true)
+
+	for _, entry := range builder.services {
+		if observer, ok := entry.Service.(StartedObserver); ok {
+			observer.MeshStarted()
+		}
+	}
+
+	return nil
+}
+
+// extensionOrder sort registered extensions by Dependent and Prioritized capabilities, the extension
+// with lowest priority whose dependencies begun is the next one, extensions with same priority keep the register order
+func (builder *meshBuilderImpl) extensionOrder() ([]Extension, error) {
+
+	graph := newDependencyGraph()
+
+	for _, extension := range builder.orderExtensions {
+		var dependencies []string
+
+		if dependent, ok := extension.(Dependent); ok {
+			dependencies = dependent.DependsOn()
+		}
+
+		for _, dependency := range dependencies {
+			if _, ok := builder.extensions[dependency]; !ok {
+				return nil, errors.Wrap(ErrNotFound, "extension %s depends on %s not found", extension.Name(), dependency)
 			}
-			builder.D("start runnable service {@service} -- success", entry.Name)
 		}
+
+		graph.addNode(extension.Name(), dependencies...)
 	}
 
-	builder.started.Store(true)
+	names, err := graph.prioritySort(func(name string) int {
+		return extensionPriority(builder.extensions[name])
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var sorted []Extension
+
+	for _, name := range names {
+		sorted = append(sorted, builder.extensions[name])
+	}
+
+	return sorted, nil
+}
+
+func extensionPriority(extension Extension) int {
+	if prioritized, ok := extension.(Prioritized); ok {
+		return prioritized.Priority()
+	}
+
+	return 0
+}
+
+// runnableOrder sort created runnable services into levels by the dependency graph derived from inject tags,
+// the dependencies will be started first. cycles between non-runnable services are allowed, the runnable
+// services in a cycle are flagged with warning and started in creation order
+func (builder *meshBuilderImpl) runnableOrder() ([][]ServiceRegisterEntry, error) {
+
+	services := make(map[string]ServiceRegisterEntry)
+
+	for _, entry := range builder.services {
+		services[entry.Name] = entry
+	}
+
+	graph := newServiceGraph(builder.services).reduce(func(name string) bool {
+		return isRunnable(services[name].Service)
+	})
+
+	levels, err := graph.sortLevels(func(cycle []string) error {
+		builder.W("runnable services dependency cycle {@cycle}, start them in creation order", strings.Join(cycle, " -> "))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var runnables [][]ServiceRegisterEntry
+
+	for _, names := range levels {
+		var level []ServiceRegisterEntry
+
+		for _, name := range names {
+			level = append(level, services[name])
+		}
+
+		runnables = append(runnables, level)
+	}
+
+	return runnables, nil
+}
+
+func (builder *meshBuilderImpl) Stop(ctx context.Context) error {
+
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+
+	return builder.shutdown(ctx)
+}
+
+// rollbackTimeout the timeout of undoing partially started mesh
+const rollbackTimeout = time.Second * 30
+
+// abort undo the partially started mesh, stop the started runnable services, stop or close the other
+// created services and teardown begun extensions, returns the start error
+func (builder *meshBuilderImpl) abort(err error) error {
+	builder.E("start mesh error, rollback: {@err}", err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	if rollbackErr := builder.shutdown(ctx); rollbackErr != nil {
+		builder.E("rollback partially started mesh error: {@err}", rollbackErr)
+	}
+
+	return err
+}
+
+// shutdown stop services and teardown extensions, the runnable services which not started are closed only.
+// the service registrations and injector are reset, so the mesh can be started again
+func (builder *meshBuilderImpl) shutdown(ctx context.Context) error {
+
+	builder.started.Store(false)
+	builder.serving.Store([]ServiceRegisterEntry(nil))
+
+	var errs MultiError
+
+	stopped := make(map[string]bool)
+
+	// the pending services are in the last started level, stop them first
+	for _, pending := range builder.pending {
+		handled, err := builder.stopPending(ctx, pending)
+
+		stopped[pending.entry.Name] = handled
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i := len(builder.runnables) - 1; i >= 0; i-- {
+		entry := builder.runnables[i]
+
+		stopped[entry.Name] = true
+
+		if err := builder.stopService(ctx, entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i := len(builder.services) - 1; i >= 0; i-- {
+		entry := builder.services[i]
+
+		if stopped[entry.Name] {
+			continue
+		}
+
+		if isRunnable(entry.Service) {
+			if err := builder.closeService(ctx, entry); err != nil {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		if err := builder.stopService(ctx, entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i := len(builder.begun) - 1; i >= 0; i-- {
+		extension := builder.begun[i]
+
+		teardown, ok := extension.(Teardown)
+
+		if !ok {
+			continue
+		}
+
+		builder.D("call extension {@ext} teardown routine", extension.Name())
+
+		if err := callWithContext(ctx, func() error { return teardown.Teardown(ctx) }); err != nil {
+			errs = append(errs, errors.Wrap(err, "extension %s teardown routine error", extension.Name()))
+			continue
+		}
+
+		builder.D("call extension {@ext} teardown routine -- success", extension.Name())
+	}
+
+	builder.bound.Store([]ServiceRegisterEntry(nil))
+	builder.injector.Store(sdi4go.New())
+	builder.registers = make(map[string][]string)
+	builder.orderServices = nil
+	builder.config = nil
+	builder.runnables = nil
+	builder.pending = nil
+	builder.services = nil
+	builder.begun = nil
+
+	return errs.errorOrNil()
+}
+
+// closeService call Close of service implements Closer without stopping it
+func (builder *meshBuilderImpl) closeService(ctx context.Context, entry ServiceRegisterEntry) error {
+	closer, ok := entry.Service.(Closer)
+
+	if !ok {
+		return nil
+	}
+
+	builder.D("close service {@service}", entry.Name)
+
+	if err := callWithContext(ctx, closer.Close); err != nil {
+		return errors.Wrap(err, "close service %s error", entry.Name)
+	}
+
+	builder.D("close service {@service} -- success", entry.Name)
+
+	return nil
+}
+
+func (builder *meshBuilderImpl) stopService(ctx context.Context, entry ServiceRegisterEntry) error {
+
+	var f func() error
+
+	switch service := entry.Service.(type) {
+	case Stoppable:
+		builder.D("stop service {@service}", entry.Name)
+		f = func() error { return service.Stop(ctx) }
+	case Closer:
+		builder.D("close service {@service}", entry.Name)
+		f = service.Close
+	default:
+		return nil
+	}
+
+	if err := callWithContext(ctx, f); err != nil {
+		return errors.Wrap(err, "stop service %s error", entry.Name)
+	}
+
+	builder.D("stop service {@service} -- success", entry.Name)
 
 	return nil
 }
 
+// callWithContext call f and wait until f returns or ctx done
+func callWithContext(ctx context.Context, f func() error) error {
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(ErrTimeout, "skipped, %s", err)
+	}
+
+	result := make(chan error, 1)
+
+	go func() {
+		result <- f()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ErrTimeout, "%s", ctx.Err())
+	}
+}
+
 var meshBuilder MeshBuilder
 var once sync.Once
 
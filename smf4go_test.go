@@ -0,0 +1,804 @@
+package smf4go
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+	_ "github.com/libs4go/scf4go/codec" //
+	"github.com/libs4go/scf4go/reader/memory"
+)
+
+type mockService struct {
+	Name     string
+	journal  *[]string
+	startErr error
+	stopErr  error
+}
+
+func (service *mockService) Start() error {
+	*service.journal = append(*service.journal, "start "+service.Name)
+	return service.startErr
+}
+
+func (service *mockService) Stop(ctx context.Context) error {
+	*service.journal = append(*service.journal, "stop "+service.Name)
+	return service.stopErr
+}
+
+// closingService runnable service which release resources by Close
+type closingService struct {
+	*mockService
+}
+
+func (service *closingService) Close() error {
+	*service.journal = append(*service.journal, "close "+service.Name)
+	return nil
+}
+
+type mockExtension struct {
+	name     string
+	services map[string]Service
+	orders   []string
+	journal  *[]string
+}
+
+func newMockExtension(name string, journal *[]string) *mockExtension {
+	return &mockExtension{
+		name:     name,
+		services: make(map[string]Service),
+		journal:  journal,
+	}
+}
+
+func (extension *mockExtension) add(name string, service Service) *mockExtension {
+	extension.services[name] = service
+	extension.orders = append(extension.orders, name)
+	return extension
+}
+
+func (extension *mockExtension) Name() string {
+	return extension.name
+}
+
+func (extension *mockExtension) Begin(config scf4go.Config, builder MeshBuilder) error {
+	for _, name := range extension.orders {
+		if err := builder.RegisterService(extension.Name(), name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (extension *mockExtension) CreateSerivce(serviceName string, config scf4go.Config) (Service, error) {
+	return extension.services[serviceName], nil
+}
+
+func (extension *mockExtension) End() error {
+	return nil
+}
+
+func (extension *mockExtension) Teardown(ctx context.Context) error {
+	*extension.journal = append(*extension.journal, "teardown "+extension.name)
+	return nil
+}
+
+func newTestConfig(t *testing.T, data string) scf4go.Config {
+	config := scf4go.New()
+
+	if err := config.Load(memory.New(memory.Data(data, "json"))); err != nil {
+		t.Fatal(err)
+	}
+
+	return config
+}
+
+func checkJournal(t *testing.T, journal []string, expect ...string) {
+	if len(journal) != len(expect) {
+		t.Fatalf("journal %v, expect %v", journal, expect)
+	}
+
+	for i := range expect {
+		if journal[i] != expect[i] {
+			t.Fatalf("journal %v, expect %v", journal, expect)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	var journal []string
+
+	stopErr := errors.New("stop error")
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &mockService{Name: "A", journal: &journal, stopErr: stopErr}).
+		add("B", &mockService{Name: "B", journal: &journal, stopErr: stopErr}))
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := builder.Stop(context.Background())
+
+	multiErr, ok := err.(MultiError)
+
+	if !ok || len(multiErr) != 2 {
+		t.Fatalf("expect aggregated stop errors, got %v", err)
+	}
+
+	checkJournal(t, journal, "start A", "start B", "stop B", "stop A", "teardown mock")
+}
+
+func TestStartRollback(t *testing.T) {
+	var journal []string
+
+	startErr := errors.New("start error")
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &mockService{Name: "A", journal: &journal}).
+		add("B", &mockService{Name: "B", journal: &journal, startErr: startErr}).
+		add("C", &closingService{mockService: &mockService{Name: "C", journal: &journal}}))
+
+	if err := builder.Start(newTestConfig(t, `{}`)); !errors.Is(err, startErr) {
+		t.Fatalf("expect start error, got %v", err)
+	}
+
+	// the unstarted C is not stopped but closed
+	checkJournal(t, journal, "start A", "start B", "stop A", "close C", "teardown mock")
+
+	var service *mockService
+
+	if err := builder.FindService("A", &service); !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("expect ErrNotStarted, got %v", err)
+	}
+}
+
+type contextService struct {
+	*mockService
+	barrier *sync.WaitGroup
+}
+
+func (service *contextService) StartContext(ctx context.Context) error {
+	*service.journal = append(*service.journal, "start "+service.Name)
+
+	if service.barrier == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	service.barrier.Done()
+	service.barrier.Wait()
+
+	return nil
+}
+
+func TestStartContext(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &mockService{Name: "A", journal: &journal}).
+		add("slow", &contextService{mockService: &mockService{Name: "slow", journal: new([]string)}}))
+
+	config := newTestConfig(t, `{"smf4go": {"service": {"slow": {"startTimeout": "10ms"}}}}`)
+
+	if err := builder.Start(config); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expect ErrTimeout, got %v", err)
+	}
+
+	checkJournal(t, journal, "start A", "stop A", "teardown mock")
+
+	journal = nil
+
+	var barrier sync.WaitGroup
+
+	barrier.Add(2)
+
+	builder = NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &contextService{mockService: &mockService{Name: "A", journal: new([]string)}, barrier: &barrier}).
+		add("B", &contextService{mockService: &mockService{Name: "B", journal: new([]string)}, barrier: &barrier}))
+
+	config = newTestConfig(t, `{"smf4go": {"start": {"parallel": true}, "service": {"A": {"startTimeout": "1s"}}}}`)
+
+	if err := builder.Start(config); err != nil {
+		t.Fatalf("expect independent services started concurrently, got %v", err)
+	}
+
+	if err := builder.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockingService runnable service which start routine can't be canceled
+type blockingService struct {
+	*mockService
+	release chan struct{}
+}
+
+func (service *blockingService) Start() error {
+	*service.journal = append(*service.journal, "start "+service.Name)
+	<-service.release
+	return nil
+}
+
+func TestStartTimeoutRollback(t *testing.T) {
+	var journal []string
+
+	slow := &blockingService{mockService: &mockService{Name: "slow", journal: &journal}, release: make(chan struct{})}
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &mockService{Name: "A", journal: &journal}).
+		add("slow", slow))
+
+	config := newTestConfig(t, `{"smf4go": {"service": {"slow": {"startTimeout": "10ms"}}}}`)
+
+	time.AfterFunc(time.Millisecond*100, func() { close(slow.release) })
+
+	if err := builder.Start(config); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expect ErrTimeout, got %v", err)
+	}
+
+	// the rollback wait the timeout start routine returned, and stop the service started by it
+	checkJournal(t, journal, "start A", "start slow", "stop slow", "stop A", "teardown mock")
+}
+
+func TestStartTwice(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &mockService{Name: "A", journal: &journal}))
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.Start(newTestConfig(t, `{}`)); !errors.Is(err, ErrExists) {
+		t.Fatalf("expect ErrExists, got %v", err)
+	}
+
+	// the running mesh is kept
+	var service *mockService
+
+	if err := builder.FindService("A", &service); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkJournal(t, journal, "start A", "stop A", "teardown mock")
+}
+
+type restartExtension struct {
+	*mockExtension
+	created int
+}
+
+func (extension *restartExtension) CreateSerivce(serviceName string, config scf4go.Config) (Service, error) {
+	extension.created++
+	return &mockService{Name: fmt.Sprintf("%s%d", serviceName, extension.created), journal: extension.journal}, nil
+}
+
+func TestRestart(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(&restartExtension{mockExtension: newMockExtension("mock", &journal).add("A", nil)})
+
+	for i := 1; i <= 2; i++ {
+		if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		var service *mockService
+
+		if err := builder.FindService("A", &service); err != nil {
+			t.Fatal(err)
+		}
+
+		if service.Name != fmt.Sprintf("A%d", i) {
+			t.Fatalf("expect service of run %d, got %s", i, service.Name)
+		}
+
+		if err := builder.Stop(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkJournal(t, journal, "start A1", "stop A1", "teardown mock", "start A2", "stop A2", "teardown mock")
+}
+
+type consumerService struct {
+	*mockService
+	Provider *mockService `inject:"provider"`
+}
+
+func TestStartOrder(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("consumer", &consumerService{mockService: &mockService{Name: "consumer", journal: &journal}}).
+		add("provider", &mockService{Name: "provider", journal: &journal}))
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkJournal(t, journal, "start provider", "start consumer", "stop consumer", "stop provider", "teardown mock")
+}
+
+func TestDependencyCycle(t *testing.T) {
+	graph := newDependencyGraph()
+
+	graph.addNode("A", "X")
+	graph.addNode("X", "B")
+	graph.addNode("B", "A")
+	graph.addNode("C")
+
+	// cycle through non-runnable node X is allowed
+	if _, err := graph.reduce(func(name string) bool { return name == "A" || name == "C" }).sort(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := graph.reduce(func(name string) bool { return name != "X" }).sort()
+
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("expect cycle error, got %v", err)
+	}
+}
+
+type serviceA struct {
+	*mockService
+	B *serviceB `inject:"B"`
+}
+
+type serviceB struct {
+	*mockService
+	A *serviceA `inject:"A"`
+}
+
+type serviceD struct {
+	*mockService
+	B *serviceB `inject:"B"`
+}
+
+func TestRunnableCycle(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	// A <-> B cycle is flagged and started in creation order, D depends on the cycle
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("D", &serviceD{mockService: &mockService{Name: "D", journal: &journal}}).
+		add("A", &serviceA{mockService: &mockService{Name: "A", journal: &journal}}).
+		add("B", &serviceB{mockService: &mockService{Name: "B", journal: &journal}}))
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkJournal(t, journal, "start A", "start B", "start D", "stop D", "stop B", "stop A", "teardown mock")
+}
+
+type orderedExtension struct {
+	*mockExtension
+	priority  int
+	dependsOn []string
+}
+
+func (extension *orderedExtension) Priority() int {
+	return extension.priority
+}
+
+func (extension *orderedExtension) DependsOn() []string {
+	return extension.dependsOn
+}
+
+func (extension *orderedExtension) Begin(config scf4go.Config, builder MeshBuilder) error {
+	*extension.journal = append(*extension.journal, "begin "+extension.name)
+	return nil
+}
+
+func TestExtensionOrder(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(&orderedExtension{mockExtension: newMockExtension("A", &journal), dependsOn: []string{"C"}})
+	builder.RegisterExtension(&orderedExtension{mockExtension: newMockExtension("B", &journal), priority: 1})
+	builder.RegisterExtension(&orderedExtension{mockExtension: newMockExtension("C", &journal), priority: 2})
+	builder.RegisterExtension(&orderedExtension{mockExtension: newMockExtension("D", &journal)})
+	// E has no dependencies but higher priority, begins after A
+	builder.RegisterExtension(&orderedExtension{mockExtension: newMockExtension("E", &journal), priority: 10})
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkJournal(t, journal,
+		"begin D", "begin B", "begin C", "begin A", "begin E",
+		"teardown E", "teardown A", "teardown C", "teardown B", "teardown D")
+}
+
+type healthService struct {
+	status HealthStatus
+}
+
+func (service *healthService) Health(ctx context.Context) HealthStatus {
+	return service.status
+}
+
+func TestHealth(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &mockService{Name: "A", journal: &journal}).
+		add("B", &healthService{status: HealthNotServing}))
+
+	if report := builder.Health(context.Background()); report.Status != HealthNotServing {
+		t.Fatalf("expect not started mesh not serving, got %s", report.Status)
+	}
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	report := builder.Health(context.Background())
+
+	if report.Status != HealthNotServing || report.Services["A"] != HealthServing || report.Services["B"] != HealthNotServing {
+		t.Fatalf("unexpected health report %v", report)
+	}
+}
+
+func TestFindService(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("A", &mockService{Name: "A", journal: &journal}))
+
+	var service *mockService
+
+	if err := builder.FindService("A", &service); !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("expect ErrNotStarted, got %v", err)
+	}
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	if err := builder.FindService("A", &service); err != nil || service.Name != "A" {
+		t.Fatalf("find service A error %v", err)
+	}
+
+	var runnable Runnable
+
+	if err := builder.FindService("A", &runnable); err != nil || runnable == nil {
+		t.Fatalf("find service A as Runnable error %v", err)
+	}
+
+	if err := builder.FindService("B", &service); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expect ErrNotFound, got %v", err)
+	}
+
+	var checker HealthChecker
+
+	if err := builder.FindService("A", &checker); !errors.Is(err, ErrType) {
+		t.Fatalf("expect ErrType, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect MustFindService panic")
+		}
+	}()
+
+	MustFindService(builder, "B", &service)
+}
+
+type typedConsumer struct {
+	*mockService
+	Checker   HealthChecker `inject:"*"`
+	Runnables []Runnable    `inject:"*"`
+}
+
+func TestFindServiceByType(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	consumer := &typedConsumer{mockService: &mockService{Name: "consumer", journal: &journal}}
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("consumer", consumer).
+		add("A", &mockService{Name: "A", journal: &journal}).
+		add("B", &healthService{status: HealthServing}))
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	// the consumer depends on runnable A by type
+	checkJournal(t, journal, "start A", "start consumer")
+
+	if consumer.Checker == nil || len(consumer.Runnables) != 1 {
+		t.Fatalf("unexpected injected fields %v %v", consumer.Checker, consumer.Runnables)
+	}
+
+	var checker HealthChecker
+
+	if err := builder.FindServiceByType(&checker); err != nil {
+		t.Fatal(err)
+	}
+
+	var runnable Runnable
+
+	if err := builder.FindServiceByType(&runnable); !errors.Is(err, ErrAmbiguous) {
+		t.Fatalf("expect ErrAmbiguous, got %v", err)
+	}
+
+	var runnables []Runnable
+
+	if err := builder.FindServices(&runnables); err != nil || len(runnables) != 2 {
+		t.Fatalf("find runnables %v error %v", runnables, err)
+	}
+}
+
+type unitOfWork struct {
+	id     int
+	closed bool
+}
+
+func (unit *unitOfWork) Close() error {
+	unit.closed = true
+	return nil
+}
+
+type unitFactory struct {
+	scope   Scope
+	created int
+}
+
+func (factory *unitFactory) Scope() Scope {
+	return factory.scope
+}
+
+func (factory *unitFactory) New() (Service, error) {
+	factory.created++
+	return &unitOfWork{id: factory.created}, nil
+}
+
+type unitConsumer struct {
+	Unit *unitOfWork `inject:"transient"`
+}
+
+func TestServiceScope(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	consumer := &unitConsumer{}
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).
+		add("consumer", consumer).
+		add("transient", &unitFactory{scope: ScopeTransient}).
+		add("context", &unitFactory{scope: ScopeContext}))
+
+	if err := builder.Start(newTestConfig(t, `{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	var a, b *unitOfWork
+
+	if err := builder.FindService("transient", &a); err != nil || consumer.Unit == nil || a == consumer.Unit {
+		t.Fatalf("expect new transient instance, error %v", err)
+	}
+
+	if err := builder.FindService("context", &a); !errors.Is(err, ErrScope) {
+		t.Fatalf("expect ErrScope, got %v", err)
+	}
+
+	ctx, release := WithServiceScope(context.Background())
+
+	if err := builder.FindServiceContext(ctx, "context", &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.FindServiceContext(ctx, "context", &b); err != nil || a != b {
+		t.Fatalf("expect same context scoped instance, error %v", err)
+	}
+
+	other, _ := WithServiceScope(context.Background())
+
+	if err := builder.FindServiceContext(other, "context", &b); err != nil || a == b {
+		t.Fatalf("expect new context scoped instance, error %v", err)
+	}
+
+	if err := release(context.Background()); err != nil || !a.closed || b.closed {
+		t.Fatalf("expect scope instance released, error %v", err)
+	}
+}
+
+type reconfigurableService struct {
+	value int
+}
+
+func (service *reconfigurableService) Reconfigure(config scf4go.Config) error {
+	value := config.Get("value").Int(0)
+
+	if value < 0 {
+		return errors.New("negative value")
+	}
+
+	service.value = value
+
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	a := &reconfigurableService{value: 1}
+	b := &reconfigurableService{value: 1}
+
+	builder.RegisterExtension(newMockExtension("mock", &journal).add("A", a).add("B", b))
+
+	if err := builder.Reload(newTestConfig(t, `{}`)); !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("expect ErrNotStarted, got %v", err)
+	}
+
+	if err := builder.Start(newTestConfig(t, `{ "smf4go": { "service": { "A": { "value": 1 }, "B": { "value": 1 } } } }`)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer builder.Stop(context.Background())
+
+	// B rejects the new config, A is rolled back
+	if err := builder.Reload(newTestConfig(t, `{ "smf4go": { "service": { "A": { "value": 2 }, "B": { "value": -1 } } } }`)); err == nil {
+		t.Fatal("expect reload error")
+	}
+
+	if a.value != 1 || b.value != 1 {
+		t.Fatalf("expect rollback, got A %d B %d", a.value, b.value)
+	}
+
+	if err := builder.Reload(newTestConfig(t, `{ "smf4go": { "service": { "A": { "value": 3 }, "B": { "value": 1 } } } }`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.value != 3 || b.value != 1 {
+		t.Fatalf("unexpected reload result A %d B %d", a.value, b.value)
+	}
+}
+
+func TestServiceSelection(t *testing.T) {
+	var journal []string
+
+	newBuilder := func() MeshBuilder {
+		journal = nil
+
+		builder := NewMeshBuilder()
+
+		builder.RegisterExtension(newMockExtension("local", &journal).
+			add("A", &mockService{Name: "local A", journal: &journal}).
+			add("B", &mockService{Name: "B", journal: &journal}))
+
+		builder.RegisterExtension(newMockExtension("remote", &journal).
+			add("A", &mockService{Name: "remote A", journal: &journal}))
+
+		return builder
+	}
+
+	if err := newBuilder().Start(newTestConfig(t, `{}`)); !errors.Is(err, ErrAmbiguous) {
+		t.Fatalf("expect ErrAmbiguous, got %v", err)
+	}
+
+	builder := newBuilder()
+
+	if err := builder.Start(newTestConfig(t, `{ "smf4go": { "service": { "A": { "extension": "remote" }, "B": { "enabled": false } } } }`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := builder.FindService("B", &mockService{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expect disabled service not found, got %v", err)
+	}
+
+	builder.Stop(context.Background())
+
+	checkJournal(t, journal, "start remote A", "stop remote A", "teardown remote", "teardown local")
+}
+
+type declaringExtension struct {
+	*mockExtension
+	declarations []ServiceDeclaration
+}
+
+func (extension *declaringExtension) Declare() []ServiceDeclaration {
+	return extension.declarations
+}
+
+func TestValidate(t *testing.T) {
+	var journal []string
+
+	builder := NewMeshBuilder()
+
+	builder.RegisterExtension(&declaringExtension{
+		mockExtension: newMockExtension("A", &journal),
+		declarations: []ServiceDeclaration{
+			{Name: "consumer", Type: reflect.TypeOf(&consumerService{})},
+			{Name: "provider"},
+			{Name: "config", Required: []string{"tls.cert"}},
+			{Name: "shared"},
+		},
+	})
+
+	builder.RegisterExtension(&declaringExtension{
+		mockExtension: newMockExtension("B", &journal),
+		declarations:  []ServiceDeclaration{{Name: "shared"}},
+	})
+
+	config := newTestConfig(t, `{ "smf4go": { "service": { "provider": { "enabled": false } } } }`)
+
+	err := builder.Validate(config)
+
+	// shared is ambiguous, config lack tls.cert, consumer inject disabled provider
+	if errs, ok := err.(MultiError); !ok || len(errs) != 3 {
+		t.Fatalf("unexpected validate result %v", err)
+	}
+
+	config = newTestConfig(t, `{ "smf4go": { "service": { "config": { "tls": { "cert": "a.crt" } }, "shared": { "extension": "B" } } } }`)
+
+	if err := builder.Validate(config); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(journal) != 0 {
+		t.Fatalf("expect mesh not started, journal %v", journal)
+	}
+}
@@ -0,0 +1,234 @@
+package smf4go
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+)
+
+// RunnableContext runnable service with context aware start routine, the ctx is canceled when
+// the start timeout smf4go.service.<name>.startTimeout expired. StartContext is called instead of
+// Start if the service implements both
+type RunnableContext interface {
+	Service
+	StartContext(ctx context.Context) error
+}
+
+// isRunnable check if service implement Runnable or RunnableContext
+func isRunnable(service Service) bool {
+	switch service.(type) {
+	case RunnableContext, Runnable:
+		return true
+	default:
+		return false
+	}
+}
+
+// serviceTiming the elapsed time of service startup phases
+type serviceTiming struct {
+	create time.Duration
+	inject time.Duration
+	start  time.Duration
+}
+
+// startupReport collect service startup timings, safe for concurrent start
+type startupReport struct {
+	sync.Mutex
+	timings map[string]*serviceTiming
+	orders  []string
+}
+
+func newStartupReport() *startupReport {
+	return &startupReport{
+		timings: make(map[string]*serviceTiming),
+	}
+}
+
+// record call f and add the elapsed time to the service timing selected by phase
+func (report *startupReport) record(name string, phase func(*serviceTiming) *time.Duration, f func() error) error {
+	begin := time.Now()
+
+	err := f()
+
+	elapsed := time.Since(begin)
+
+	report.Lock()
+	defer report.Unlock()
+
+	timing, ok := report.timings[name]
+
+	if !ok {
+		timing = &serviceTiming{}
+		report.timings[name] = timing
+		report.orders = append(report.orders, name)
+	}
+
+	*phase(timing) += elapsed
+
+	return err
+}
+
+func createPhase(timing *serviceTiming) *time.Duration { return &timing.create }
+func injectPhase(timing *serviceTiming) *time.Duration { return &timing.inject }
+func startPhase(timing *serviceTiming) *time.Duration  { return &timing.start }
+
+// log write the report by service creation order, begin is the mesh start time
+func (report *startupReport) log(builder *meshBuilderImpl, begin time.Time) {
+	report.Lock()
+	defer report.Unlock()
+
+	for _, name := range report.orders {
+		timing := report.timings[name]
+
+		builder.I("service {@service} startup: create {@create}, inject {@inject}, start {@start}",
+			name, timing.create.String(), timing.inject.String(), timing.start.String())
+	}
+
+	builder.I("mesh startup elapsed {@elapsed}", time.Since(begin).String())
+}
+
+// startServices start runnable services level by level, the services in the same level are
+// independent of each other and started concurrently if smf4go.start.parallel is true.
+// the services started successfully are recorded for stopping even if others in the level failed,
+// so are the services which start routine timeout
+func (builder *meshBuilderImpl) startServices(config scf4go.Config, levels [][]ServiceRegisterEntry, report *startupReport) error {
+
+	parallel := config.Get("smf4go", "start", "parallel").Bool(false)
+
+	for _, level := range levels {
+		errs := make([]error, len(level))
+		started := make([]bool, len(level))
+		pending := make([]*pendingStart, len(level))
+
+		start := func(i int) {
+			pending[i], errs[i] = builder.startService(config, level[i], report)
+			started[i] = errs[i] == nil
+		}
+
+		if parallel && len(level) > 1 {
+			var wg sync.WaitGroup
+
+			for i := range level {
+				wg.Add(1)
+
+				go func(i int) {
+					defer wg.Done()
+					start(i)
+				}(i)
+			}
+
+			wg.Wait()
+		} else {
+			for i := range level {
+				if start(i); errs[i] != nil {
+					break
+				}
+			}
+		}
+
+		var failed MultiError
+
+		for i, entry := range level {
+			if started[i] {
+				builder.runnables = append(builder.runnables, entry)
+			} else if errs[i] != nil {
+				failed = append(failed, errs[i])
+			}
+
+			if pending[i] != nil {
+				builder.pending = append(builder.pending, pending[i])
+			}
+		}
+
+		if len(failed) == 1 {
+			return failed[0]
+		}
+
+		if err := failed.errorOrNil(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingStart runnable service which start routine not returned when start timeout expired,
+// the service may be started later, result receives the start routine result
+type pendingStart struct {
+	entry  ServiceRegisterEntry
+	result <-chan error
+}
+
+// startService start runnable service with timeout smf4go.service.<name>.startTimeout, no timeout if
+// it's not positive. the start routine still running when timeout expired is returned as pending,
+// the service is stopped by rollback once it's started
+func (builder *meshBuilderImpl) startService(config scf4go.Config, entry ServiceRegisterEntry, report *startupReport) (*pendingStart, error) {
+
+	timeout := config.Get("smf4go", "service", entry.Name, "startTimeout").Duration(0)
+
+	ctx, cancel := context.Background(), func() {}
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	defer cancel()
+
+	builder.D("start runnable service {@service}", entry.Name)
+
+	var pending *pendingStart
+
+	err := report.record(entry.Name, startPhase, func() error {
+		var start func() error
+
+		switch service := entry.Service.(type) {
+		case RunnableContext:
+			start = func() error { return service.StartContext(ctx) }
+		case Runnable:
+			start = service.Start
+		default:
+			return nil
+		}
+
+		result := make(chan error, 1)
+
+		go func() {
+			result <- start()
+		}()
+
+		select {
+		case err := <-result:
+			return err
+		case <-ctx.Done():
+			pending = &pendingStart{entry: entry, result: result}
+			return errors.Wrap(ErrTimeout, "%s", ctx.Err())
+		}
+	})
+
+	if err != nil {
+		return pending, errors.Wrap(err, "start service %s error", entry.Name)
+	}
+
+	builder.D("start runnable service {@service} -- success", entry.Name)
+
+	return nil, nil
+}
+
+// stopPending wait the pending start routine return and stop the service if it's started,
+// returns false if the start routine failed, the service is released as not started one
+func (builder *meshBuilderImpl) stopPending(ctx context.Context, pending *pendingStart) (bool, error) {
+	select {
+	case err := <-pending.result:
+		if err != nil {
+			return false, nil
+		}
+	case <-ctx.Done():
+		// the service which start routine still running is abandoned
+		return true, errors.Wrap(ErrTimeout, "wait service %s start routine error: %s", pending.entry.Name, ctx.Err())
+	}
+
+	return true, builder.stopService(ctx, pending.entry)
+}
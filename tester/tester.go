@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/libs4go/scf4go"
 	_ "github.com/libs4go/scf4go/codec" //
@@ -40,6 +42,7 @@ type Tester interface {
 	B() *testing.B
 	LocalService() localservice.LocalService
 	GRPCService() grpcservice.Register
+	GRPCAddr() string
 	Context() context.Context
 }
 
@@ -52,8 +55,12 @@ type testerImpl struct {
 	ls          localservice.LocalService
 	gs          grpcservice.Register
 	meshBuilder smf4go.MeshBuilder
+	grpcAddr    string
 }
 
+// testers tester instances counter, used to create unique memory listener name
+var testers int32
+
 func newTester(t *testing.T, b *testing.B) Tester {
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -62,7 +69,7 @@ func newTester(t *testing.T, b *testing.B) Tester {
 
 	localservice := localservice.New(meshBuilder)
 
-	grpcservice := grpcservice.New(
+	register := grpcservice.New(
 		"tester.grpc",
 		grpcservice.WithMeshBuilder(meshBuilder),
 		grpcservice.WithLocalService(localservice),
@@ -76,7 +83,8 @@ func newTester(t *testing.T, b *testing.B) Tester {
 		cancel:      cancel,
 		meshBuilder: meshBuilder,
 		ls:          localservice,
-		gs:          grpcservice,
+		gs:          register,
+		grpcAddr:    fmt.Sprintf("smf4go.tester.%d", atomic.AddInt32(&testers, 1)),
 	}
 
 	tester.Config(`{ "slf4go": {
@@ -93,6 +101,16 @@ func newTester(t *testing.T, b *testing.B) Tester {
 		}
     }}`, "json")
 
+	// grpc server of tester listen on in-process memory listener
+	tester.Config(fmt.Sprintf(`{ "smf4go": {
+		"service": {
+			"%s": {
+				"network": "memory",
+				"laddr": "%s"
+			}
+		}
+	}}`, grpcservice.DefaultProvider, tester.grpcAddr), "json")
+
 	return tester
 }
 
@@ -125,18 +143,37 @@ func (tester *testerImpl) Run(f F) {
 	})
 
 	if err := slf4go.Config(tester.config.SubConfig("slf4go")); err != nil {
-		println(fmt.Sprintf("set slf4go config error: %s", err))
+		tester.fatalf("set slf4go config error: %s", err)
 		return
 	}
 
 	if err := tester.meshBuilder.Start(tester.config); err != nil {
-		println(fmt.Sprintf("run tester error: %s", err))
+		tester.fatalf("run tester error: %s", err)
 		return
 	}
 
 	defer slf4go.Sync()
 
 	<-tester.ctx.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if err := tester.meshBuilder.Stop(ctx); err != nil {
+		println(fmt.Sprintf("stop tester error: %s", err))
+	}
+}
+
+// fatalf fail the test or benchmark running the tester
+func (tester *testerImpl) fatalf(format string, args ...interface{}) {
+	switch {
+	case tester.t != nil:
+		tester.t.Fatalf(format, args...)
+	case tester.b != nil:
+		tester.b.Fatalf(format, args...)
+	default:
+		println(fmt.Sprintf(format, args...))
+	}
 }
 
 func (tester *testerImpl) Stop() {
@@ -159,6 +196,11 @@ func (tester *testerImpl) GRPCService() grpcservice.Register {
 	return tester.gs
 }
 
+// GRPCAddr get the tester's grpc server address, which can be dialed by GRPCService().Dial
+func (tester *testerImpl) GRPCAddr() string {
+	return "memory:" + tester.grpcAddr
+}
+
 func (tester *testerImpl) Context() context.Context {
 	return tester.ctx
 }
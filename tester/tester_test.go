@@ -1,6 +1,23 @@
 package tester
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libs4go/scf4go"
+	"github.com/libs4go/smf4go"
+	"github.com/libs4go/smf4go/service/grpcservice"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
 
 func TestTester(t *testing.T) {
 	T(t).Run(EmptyTesterTest)
@@ -9,3 +26,315 @@ func TestTester(t *testing.T) {
 func EmptyTesterTest(tester Tester) {
 	tester.Stop()
 }
+
+type echoService struct {
+	tester Tester
+}
+
+func (service *echoService) GrpcHandler(server *grpc.Server) error {
+	return nil
+}
+
+// MeshStarted check the grpc health status is published once the mesh started
+func (service *echoService) MeshStarted() {
+	go func() {
+		defer service.tester.Stop()
+
+		ctx, cancel := context.WithTimeout(service.tester.Context(), time.Second*5)
+		defer cancel()
+
+		conn, err := service.tester.GRPCService().Dial(ctx, service.tester.GRPCAddr(), grpc.WithInsecure())
+
+		if err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		defer conn.Close()
+
+		response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "tester.echo"}, grpc.WaitForReady(true))
+
+		if err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		if response.Status != healthpb.HealthCheckResponse_SERVING {
+			service.tester.T().Errorf("expect SERVING, got %s", response.Status)
+		}
+	}()
+}
+
+func TestGRPCMemoryProvider(t *testing.T) {
+	T(t).Run(func(tester Tester) {
+		tester.GRPCService().Local("tester.echo", func(config scf4go.Config) (grpcservice.Service, error) {
+			return &echoService{tester: tester}, nil
+		})
+	})
+}
+
+// pingService Local grpc service smf4go.tester.Ping, reply the request metadata "who" as health status service field
+type pingService struct {
+}
+
+func (service *pingService) GrpcHandler(server *grpc.Server) error {
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "smf4go.tester.Ping",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Ping",
+				Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+					request := &healthpb.HealthCheckRequest{}
+
+					if err := dec(request); err != nil {
+						return nil, err
+					}
+
+					handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+						md, _ := metadata.FromIncomingContext(ctx)
+
+						return &healthpb.HealthCheckRequest{Service: req.(*healthpb.HealthCheckRequest).Service + " " + md.Get("who")[0]}, nil
+					}
+
+					// call server interceptors like generated code
+					if interceptor == nil {
+						return handler(ctx, request)
+					}
+
+					return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/smf4go.tester.Ping/Ping"}, handler)
+				},
+			},
+		},
+	}, service)
+
+	return nil
+}
+
+type pingClient struct {
+	tester Tester
+	conn   *grpc.ClientConn
+}
+
+func (client *pingClient) Start() error {
+	go func() {
+		defer client.tester.Stop()
+
+		ctx, cancel := context.WithTimeout(client.tester.Context(), time.Second*5)
+		defer cancel()
+
+		ctx = metadata.AppendToOutgoingContext(ctx, "who", "tester")
+
+		response := &healthpb.HealthCheckRequest{}
+
+		if err := client.conn.Invoke(ctx, "/smf4go.tester.Ping/Ping", &healthpb.HealthCheckRequest{Service: "ping"}, response); err != nil {
+			client.tester.T().Error(err)
+			return
+		}
+
+		if response.Service != "ping tester" {
+			client.tester.T().Errorf("unexpected response %s", response.Service)
+		}
+	}()
+
+	return nil
+}
+
+func TestGRPCInProcess(t *testing.T) {
+	tester := T(t)
+
+	// the remote address is the tester's grpc server, the call is short-circuited to the Local service
+	tester.Config(fmt.Sprintf(`{ "smf4go": { "service": { "tester.ping.client": { "remote": "%s" } } } }`, tester.GRPCAddr()), "json")
+
+	var caller atomic.Value
+
+	tester.Run(func(tester Tester) {
+		tester.GRPCService().Intercept(grpcservice.Interceptors{
+			UnaryServer: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if p, ok := peer.FromContext(ctx); ok {
+					caller.Store(p.Addr.String())
+				}
+
+				return handler(ctx, req)
+			},
+		})
+
+		tester.GRPCService().Local("tester.ping", func(config scf4go.Config) (grpcservice.Service, error) {
+			return &pingService{}, nil
+		})
+
+		tester.GRPCService().Remote("tester.ping.client", func(conn *grpc.ClientConn) (smf4go.Service, error) {
+			return &pingClient{tester: tester, conn: conn}, nil
+		})
+	})
+
+	// the call is carried by the in-process endpoint of tester's grpc server
+	if addr, _ := caller.Load().(string); addr != "memory:inproc.tester.grpc" {
+		t.Fatalf("expect in-process call, got peer %s", addr)
+	}
+}
+
+type stateClient struct {
+	tester Tester
+}
+
+func (client *stateClient) Start() error {
+	go func() {
+		defer client.tester.Stop()
+
+		if _, err := client.tester.GRPCService().State("tester.unknown"); err == nil {
+			client.tester.T().Error("expect unknown remote service error")
+		}
+
+		ctx, cancel := context.WithTimeout(client.tester.Context(), time.Second*5)
+		defer cancel()
+
+		for {
+			state, err := client.tester.GRPCService().State("tester.state.client")
+
+			if err != nil {
+				client.tester.T().Error(err)
+				return
+			}
+
+			if state == connectivity.Ready {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				client.tester.T().Errorf("connection not ready, state %s", state)
+				return
+			case <-time.After(time.Millisecond * 50):
+			}
+		}
+	}()
+
+	return nil
+}
+
+func TestGRPCConnectionState(t *testing.T) {
+	tester := T(t)
+
+	// the tester's grpc server is not serving before the mesh started, the eager connection wait timeout
+	tester.Config(fmt.Sprintf(`{ "smf4go": { "service": { "tester.state.client": {
+		"remote": "%s", "connect": "eager", "waitForReady": "100ms", "backoff": { "baseDelay": "100ms", "maxDelay": "1s" }
+	} } } }`, tester.GRPCAddr()), "json").Run(func(tester Tester) {
+		tester.GRPCService().Remote("tester.state.client", func(conn *grpc.ClientConn) (smf4go.Service, error) {
+			return &stateClient{tester: tester}, nil
+		})
+	})
+}
+
+type addrService struct {
+	tester Tester
+}
+
+func (service *addrService) Start() error {
+	go func() {
+		defer service.tester.Stop()
+
+		ctx, cancel := context.WithTimeout(service.tester.Context(), time.Second*5)
+		defer cancel()
+
+		addr, err := service.tester.GRPCService().ServerAddr(ctx)
+
+		if err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		if addr.(*net.TCPAddr).Port == 0 {
+			service.tester.T().Errorf("expect bound port, got %s", addr)
+			return
+		}
+
+		conn, err := service.tester.GRPCService().Dial(ctx, addr.String(), grpc.WithInsecure())
+
+		if err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		defer conn.Close()
+
+		if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true)); err != nil {
+			service.tester.T().Error(err)
+		}
+	}()
+
+	return nil
+}
+
+func TestGRPCServerAddr(t *testing.T) {
+	T(t).Config(fmt.Sprintf(`{ "smf4go": { "service": { "%s": { "network": "tcp", "laddr": "127.0.0.1:0" } } } }`, grpcservice.DefaultProvider), "json").Run(func(tester Tester) {
+		tester.LocalService().Register("tester.addr", func(config scf4go.Config) (smf4go.Service, error) {
+			return &addrService{tester: tester}, nil
+		})
+	})
+}
+
+type reflectionService struct {
+	tester Tester
+}
+
+func (service *reflectionService) Start() error {
+	go func() {
+		defer service.tester.Stop()
+
+		ctx, cancel := context.WithTimeout(service.tester.Context(), time.Second*5)
+		defer cancel()
+
+		conn, err := service.tester.GRPCService().Dial(ctx, service.tester.GRPCAddr(), grpc.WithInsecure())
+
+		if err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		defer conn.Close()
+
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx, grpc.WaitForReady(true))
+
+		if err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		if err := stream.Send(&reflectionpb.ServerReflectionRequest{MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{}}); err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		response, err := stream.Recv()
+
+		if err != nil {
+			service.tester.T().Error(err)
+			return
+		}
+
+		services := make(map[string]bool)
+
+		for _, service := range response.GetListServicesResponse().GetService() {
+			services[service.Name] = true
+		}
+
+		if !services["smf4go.tester.Ping"] || !services["grpc.channelz.v1.Channelz"] {
+			service.tester.T().Errorf("unexpected services %v", services)
+		}
+	}()
+
+	return nil
+}
+
+func TestGRPCReflection(t *testing.T) {
+	T(t).Config(`{ "smf4go": { "extension": { "smf4go.extension.mxwservice.tester.grpc": { "reflection": true, "channelz": true } } } }`, "json").Run(func(tester Tester) {
+		tester.GRPCService().Local("tester.ping", func(config scf4go.Config) (grpcservice.Service, error) {
+			return &pingService{}, nil
+		})
+
+		tester.LocalService().Register("tester.reflection", func(config scf4go.Config) (smf4go.Service, error) {
+			return &reflectionService{tester: tester}, nil
+		})
+	})
+}
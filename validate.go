@@ -0,0 +1,123 @@
+package smf4go
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/libs4go/errors"
+	"github.com/libs4go/scf4go"
+)
+
+// ServiceDeclaration the service which will be registered by extension
+type ServiceDeclaration struct {
+	Name     string       // service name
+	Type     reflect.Type // service type, the inject tags of struct pointer type are checked, nil if unknown
+	Required []string     // required config keys of smf4go.service.<name>, nested keys are separated by dot
+}
+
+// Declarer extension which declares its services, so MeshBuilder.Validate can check them
+// without calling extension Begin routine
+type Declarer interface {
+	Declare() []ServiceDeclaration
+}
+
+// hasConfig check config value exists
+func hasConfig(config scf4go.Config, path ...string) bool {
+	var value interface{}
+
+	config.Get(path...).Scan(&value)
+
+	return value != nil
+}
+
+// Validate check extensions dependencies, services declared by Declarer extensions can be selected,
+// their required config exist and their inject tags resolve to enabled services. the services of
+// extensions not implement Declarer are unknown, the inject tags can't be resolved are warned only
+func (builder *meshBuilderImpl) Validate(config scf4go.Config) error {
+
+	builder.mutex.Lock()
+	defer builder.mutex.Unlock()
+
+	var errs MultiError
+
+	if _, err := builder.extensionOrder(); err != nil {
+		errs = append(errs, err)
+	}
+
+	var names []string
+
+	candidates := make(map[string][]string)
+	declarations := make(map[string]map[string]ServiceDeclaration)
+	complete := true
+
+	for _, extension := range builder.orderExtensions {
+		declarer, ok := extension.(Declarer)
+
+		if !ok {
+			builder.W("extension {@ext} not declare services, skip validating them", extension.Name())
+			complete = false
+			continue
+		}
+
+		for _, declaration := range declarer.Declare() {
+			if len(candidates[declaration.Name]) == 0 {
+				names = append(names, declaration.Name)
+				declarations[declaration.Name] = make(map[string]ServiceDeclaration)
+			}
+
+			candidates[declaration.Name] = append(candidates[declaration.Name], extension.Name())
+			declarations[declaration.Name][extension.Name()] = declaration
+		}
+	}
+
+	enabled := make(map[string]bool)
+
+	var selected []ServiceDeclaration
+
+	for _, name := range names {
+		subconfig := config.SubConfig("smf4go", "service", name)
+
+		if !subconfig.Get("enabled").Bool(true) {
+			continue
+		}
+
+		extension, err := selectCandidate(subconfig, name, candidates[name])
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		enabled[name] = true
+
+		declaration := declarations[name][extension]
+
+		for _, key := range declaration.Required {
+			if !hasConfig(subconfig, strings.Split(key, ".")...) {
+				errs = append(errs, errors.Wrap(ErrConfig, "service %s required config %s not found", name, key))
+			}
+		}
+
+		selected = append(selected, declaration)
+	}
+
+	for _, declaration := range selected {
+		for _, field := range injectFields(declaration.Type) {
+			name := field.Tag.Get("inject")
+
+			if name == injectByType || enabled[name] {
+				continue
+			}
+
+			if _, ok := candidates[name]; ok {
+				errs = append(errs, errors.Wrap(ErrNotFound, "service %s inject service %s which is not enabled", declaration.Name, name))
+			} else if complete {
+				errs = append(errs, errors.Wrap(ErrNotFound, "service %s inject service %s not registered", declaration.Name, name))
+			} else {
+				builder.W("service {@service} inject service {@inject} not declared", declaration.Name, name)
+			}
+		}
+	}
+
+	return errs.errorOrNil()
+}